@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/yy4382/dns-set/internal/app"
+	"github.com/yy4382/dns-set/internal/domain"
+	"github.com/yy4382/dns-set/internal/output"
+	"github.com/yy4382/dns-set/internal/ui"
+	"github.com/yy4382/dns-set/pkg/dnsset"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list [name...]",
+	Short: "Show the current A/AAAA records of domains",
+	Long: `list shows the current A and AAAA records with their TTL and proxy status,
+and whether they point at the address of this host as found by the
+configured IP detector (sync.ip_detector).
+
+The records listed are those of the given names, of a whole zone (--zone),
+or of the domains found in a Caddyfile (--caddyfile). Without any of them,
+the domains of the configured sync are listed.`,
+	RunE: runList,
+}
+
+func init() {
+	listCmd.Flags().String("zone", "", "List all records of this zone")
+	listCmd.Flags().String("caddyfile", "", "List the domains of this Caddyfile (default preferences.caddyfile_path)")
+	listCmd.Flags().Lookup("caddyfile").NoOptDefVal = "-"
+	listCmd.Flags().Bool("no-detect", false, "Do not compare the records with the addresses of this host")
+	rootCmd.AddCommand(listCmd)
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+	zone, _ := cmd.Flags().GetString("zone")
+	caddyfilePath, _ := cmd.Flags().GetString("caddyfile")
+	noDetect, _ := cmd.Flags().GetBool("no-detect")
+
+	printer, err := newPrinter(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return err
+	}
+
+	spec := dnsset.ListSpec{Names: args, Zone: zone, Provider: provider}
+
+	if caddyfilePath != "" {
+		if caddyfilePath == "-" {
+			caddyfilePath = cfg.Preferences.CaddyfilePath
+		}
+
+		domains, err := domain.NewCaddyfileSource(caddyfilePath).GetDomains()
+		if err != nil {
+			return fmt.Errorf("failed to get domains from Caddyfile: %w", err)
+		}
+		spec.Names = append(spec.Names, domains...)
+	}
+
+	if len(spec.Names) == 0 && spec.Zone == "" {
+		source, err := app.NewDomainSource(cfg)
+		if err != nil {
+			return err
+		}
+
+		spec.Names, err = source.GetDomains()
+		if err != nil {
+			return fmt.Errorf("failed to get domains from %s: %w", source.Name(), err)
+		}
+	}
+
+	if !noDetect {
+		spec.Detector, err = app.NewDetector(cfg, cfg.Sync.IPDetector)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := dnsset.List(cmd.Context(), spec)
+	if err != nil {
+		return fmt.Errorf("failed to list DNS records: %w", err)
+	}
+
+	if printer.Structured() {
+		if err := printer.Print(result); err != nil {
+			return err
+		}
+	} else {
+		ui.PrintRecords(os.Stdout, result)
+	}
+
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("failed to list records for %d names: %w", len(result.Errors), output.ErrReported)
+	}
+
+	return nil
+}
@@ -1,39 +1,70 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/yy4382/dns-set/internal/config"
-	"github.com/yy4382/dns-set/internal/dns"
+	"github.com/yy4382/dns-set/internal/output"
 	"github.com/yy4382/dns-set/internal/ui"
+	"github.com/yy4382/dns-set/pkg/dnsset"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "dns-set",
 	Short: "A tool for managing DNS records on DNS providers",
 	Long: `dns-set is a command-line tool for automatically managing DNS records.
-It can read domains from multiple sources (manual input, Caddyfile),
+It can read domains from multiple sources (manual input, Caddyfile, Caddy
+JSON config, nginx, Traefik, Kubernetes manifests),
 detect IP addresses through various methods (network interface, API, manual),
 and update DNS records on supported providers (currently Cloudflare).`,
-	RunE: runDNSSet,
+	RunE:          runDNSSet,
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
 func init() {
-	rootCmd.Flags().StringP("config", "c", "", "Config file path")
+	rootCmd.PersistentFlags().StringP("config", "c", "", "Config file path")
+	rootCmd.PersistentFlags().StringP("output", "o", "text", "Output format: text, json or yaml")
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		reportError(err)
 		os.Exit(1)
 	}
 }
 
-func runDNSSet(cmd *cobra.Command, args []string) error {
-	configPath, _ := cmd.Flags().GetString("config")
+// reportError writes err as an error document in the structured formats,
+// and as text otherwise, including when --output itself is invalid.
+func reportError(err error) {
+	value, _ := rootCmd.PersistentFlags().GetString("output")
+	format, parseErr := output.ParseFormat(value)
+
+	if parseErr == nil && format != output.FormatText {
+		if !errors.Is(err, output.ErrReported) {
+			output.NewPrinter(os.Stdout, format).Print(output.Error{Error: err.Error()})
+		}
+		return
+	}
 
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+}
+
+func newPrinter(cmd *cobra.Command) (*output.Printer, error) {
+	value, _ := cmd.Flags().GetString("output")
+
+	format, err := output.ParseFormat(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.NewPrinter(os.Stdout, format), nil
+}
+
+func loadConfig(configPath string) (*config.Config, error) {
 	var cfg *config.Config
 	var err error
 
@@ -44,13 +75,44 @@ func runDNSSet(cmd *cobra.Command, args []string) error {
 	}
 
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// newProvider creates the provider for the non-interactive commands, which
+// cannot prompt for a missing API token.
+func newProvider(cfg *config.Config) (dnsset.DNSProvider, error) {
+	if cfg.Cloudflare.APIToken == "" {
+		return nil, fmt.Errorf("no Cloudflare API token configured; run dns-set once interactively or set CLOUDFLARE_API_TOKEN")
+	}
+
+	provider, err := dnsset.NewCloudflareProvider(cfg.Cloudflare.APIToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Cloudflare provider: %w", err)
+	}
+
+	return provider, nil
+}
+
+func runDNSSet(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+
+	printer, err := newPrinter(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
 	}
 
 	apiToken := cfg.Cloudflare.APIToken
 
 	if apiToken == "" {
-		tempCLI := ui.NewCLI(cfg, nil)
+		tempCLI := ui.NewCLI(cfg, nil, printer)
 
 		promptedToken, err := tempCLI.PromptAndSaveAPIToken(configPath)
 		if err != nil {
@@ -59,22 +121,17 @@ func runDNSSet(cmd *cobra.Command, args []string) error {
 
 		apiToken = promptedToken
 
-		if configPath != "" {
-			cfg, err = config.LoadWithConfigPath(configPath)
-		} else {
-			cfg, err = config.Load()
-		}
-
+		cfg, err = loadConfig(configPath)
 		if err != nil {
 			return fmt.Errorf("failed to reload configuration: %w", err)
 		}
 	}
 
-	provider, err := dns.NewCloudflareProvider(apiToken)
+	provider, err := dnsset.NewCloudflareProvider(apiToken)
 	if err != nil {
 		return fmt.Errorf("failed to initialize Cloudflare provider: %w", err)
 	}
 
-	cli := ui.NewCLI(cfg, provider)
+	cli := ui.NewCLI(cfg, provider, printer)
 	return cli.Run()
 }
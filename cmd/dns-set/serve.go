@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/yy4382/dns-set/internal/server"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run an HTTP server for dyndns2 updates and the management API",
+	Long: `serve starts an HTTP server exposing a dyndns2-compatible /nic/update
+endpoint, so routers (OpenWrt, FritzBox, pfSense, ...) can push their
+address to dns-set, which then updates the records on the DNS provider.
+
+When server.api.token is set, it also serves a JSON management API under
+/api/v1 to list the configured domains, detect addresses, trigger a sync
+and update single records.
+
+With --output json or yaml, every log event is written as a document.`,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().String("listen", "", "Address to listen on (overrides server.listen)")
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+
+	printer, err := newPrinter(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	if listen, _ := cmd.Flags().GetString("listen"); listen != "" {
+		cfg.Server.Listen = listen
+	}
+
+	if len(cfg.Server.DynDNS.Users) == 0 && cfg.Server.API.Token == "" {
+		return fmt.Errorf("nothing to serve: configure server.dyndns.users or server.api.token")
+	}
+
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return err
+	}
+
+	return server.New(cfg, provider, printer).ListenAndServe()
+}
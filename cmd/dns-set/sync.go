@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/yy4382/dns-set/internal/app"
+	"github.com/yy4382/dns-set/internal/output"
+	"github.com/yy4382/dns-set/internal/ui"
+	"github.com/yy4382/dns-set/pkg/dnsset"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Update the records described in the sync section of the config",
+	Long: `sync runs without prompts: it reads the domains, IP detector, record types
+and proxy status from the sync section of the config file and updates the
+records. It exits non-zero when any record could not be updated.
+
+Records applied before are remembered in a state file, so a run in which no
+address changed makes no calls to the DNS provider. --refresh checks every
+record with the provider and rewrites the state.`,
+	RunE: runSync,
+}
+
+func init() {
+	syncCmd.Flags().Bool("refresh", false, "Check every record with the provider instead of trusting the state file")
+	rootCmd.AddCommand(syncCmd)
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+
+	printer, err := newPrinter(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return err
+	}
+
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	provider, err = app.StateProvider(cfg, provider, refresh)
+	if err != nil {
+		return err
+	}
+
+	spec, err := app.SpecFromConfig(cfg, provider)
+	if err != nil {
+		return err
+	}
+
+	result, err := dnsset.Sync(cmd.Context(), spec)
+	if err != nil {
+		return fmt.Errorf("failed to sync DNS records: %w", err)
+	}
+
+	if printer.Structured() {
+		if err := printer.Print(result); err != nil {
+			return err
+		}
+	} else {
+		ui.PrintResult(os.Stdout, result)
+	}
+
+	if failed := result.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d records failed to update: %w", failed, len(result.Records), output.ErrReported)
+	}
+
+	return nil
+}
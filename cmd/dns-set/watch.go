@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/yy4382/dns-set/internal/app"
+	"github.com/yy4382/dns-set/internal/config"
+	"github.com/yy4382/dns-set/internal/ui"
+	"github.com/yy4382/dns-set/internal/watch"
+	"github.com/yy4382/dns-set/pkg/dnsset"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Keep the records of the sync section up to date as addresses change",
+	Long: `watch runs the sync described in the sync section of the config once, then
+again whenever a public address on the interfaces selected by ip.interface
+is added or removed, and every interval as a fallback.
+
+On Linux the address changes come from rtnetlink and are debounced, so a
+renumbering syncs once. Elsewhere watch only polls.
+
+Like sync, watch trusts the state file for records applied before; with
+--refresh every sync checks each record with the provider.`,
+	RunE: runWatch,
+}
+
+func init() {
+	watchCmd.Flags().Duration("interval", 0, "Sync at least this often (overrides watch.interval)")
+	watchCmd.Flags().Duration("debounce", 0, "Wait this long after the last address change (overrides watch.debounce)")
+	watchCmd.Flags().Bool("refresh", false, "Check every record with the provider on each sync instead of trusting the state file")
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+
+	printer, err := newPrinter(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	if interval, _ := cmd.Flags().GetDuration("interval"); interval > 0 {
+		cfg.Watch.Interval = interval
+	}
+	if debounce, _ := cmd.Flags().GetDuration("debounce"); debounce > 0 {
+		cfg.Watch.Debounce = debounce
+	}
+
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return err
+	}
+
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	provider, err = app.StateProvider(cfg, provider, refresh)
+	if err != nil {
+		return err
+	}
+
+	// Fail early on a broken sync section instead of on the first event.
+	if _, err := app.SpecFromConfig(cfg, provider); err != nil {
+		return err
+	}
+
+	filter, err := app.WatchFilter(cfg)
+	if err != nil {
+		return err
+	}
+
+	watcher := watch.New(cfg.Watch.Interval, cfg.Watch.Debounce)
+	watcher.Filter = filter
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return watcher.Run(ctx, func(ctx context.Context, reason string) {
+		log.Printf("syncing (%s)", reason)
+
+		result, err := syncOnce(ctx, cfg, provider)
+		if err != nil {
+			log.Printf("sync failed: %v", err)
+			return
+		}
+
+		if printer.Structured() {
+			printer.Print(result)
+		} else {
+			ui.PrintResult(os.Stdout, result)
+		}
+	})
+}
+
+// syncOnce reads the domains again, so a changed domain source is picked up
+// without a restart.
+func syncOnce(ctx context.Context, cfg *config.Config, provider dnsset.DNSProvider) (*dnsset.Result, error) {
+	spec, err := app.SpecFromConfig(cfg, provider)
+	if err != nil {
+		return nil, err
+	}
+	return dnsset.Sync(ctx, spec)
+}
@@ -0,0 +1,350 @@
+// Package app wires the configuration file to the dnsset API: it builds the
+// spec of the configured sync and the detectors and domain sources named in
+// the config.
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/yy4382/dns-set/internal/config"
+	"github.com/yy4382/dns-set/internal/domain"
+	"github.com/yy4382/dns-set/internal/ip"
+	"github.com/yy4382/dns-set/internal/state"
+	"github.com/yy4382/dns-set/internal/watch"
+	"github.com/yy4382/dns-set/pkg/dnsset"
+)
+
+// SpecFromConfig builds the spec of the non-interactive sync described by
+// the sync section of cfg. It reads the domains from the configured source.
+func SpecFromConfig(cfg *config.Config, provider dnsset.DNSProvider) (dnsset.Spec, error) {
+	source, err := NewDomainSource(cfg)
+	if err != nil {
+		return dnsset.Spec{}, err
+	}
+
+	domains, err := source.GetDomains()
+	if err != nil {
+		return dnsset.Spec{}, fmt.Errorf("failed to get domains from %s: %w", source.Name(), err)
+	}
+
+	detector, err := NewDetector(cfg, cfg.Sync.IPDetector)
+	if err != nil {
+		return dnsset.Spec{}, err
+	}
+
+	recordTypes, err := ParseRecordTypes(cfg.Sync.RecordTypes)
+	if err != nil {
+		return dnsset.Spec{}, err
+	}
+
+	hosts, err := hostsFromConfig(cfg)
+	if err != nil {
+		return dnsset.Spec{}, err
+	}
+	for _, host := range cfg.Sync.Hosts {
+		if !slices.Contains(domains, host.Domain) {
+			domains = append(domains, host.Domain)
+		}
+	}
+
+	return dnsset.Spec{
+		Domains:     domains,
+		RecordTypes: recordTypes,
+		Detector:    detector,
+		Provider:    provider,
+		TTL:         cfg.Preferences.DefaultTTL,
+		Proxied:     cfg.Sync.Proxied,
+		Hosts:       hosts,
+	}, nil
+}
+
+func hostsFromConfig(cfg *config.Config) (map[string]dnsset.Host, error) {
+	if len(cfg.Sync.Hosts) == 0 {
+		return nil, nil
+	}
+
+	hosts := make(map[string]dnsset.Host)
+	// Hosts naming the same detector share it, so it is asked only once.
+	detectors := make(map[string]ip.IPDetector)
+	for _, hostConfig := range cfg.Sync.Hosts {
+		if hostConfig.Domain == "" {
+			return nil, fmt.Errorf("sync host needs a domain")
+		}
+		if _, exists := hosts[hostConfig.Domain]; exists {
+			return nil, fmt.Errorf("sync host %s is listed twice", hostConfig.Domain)
+		}
+
+		host := dnsset.Host{IPv6PrefixLength: hostConfig.IPv6PrefixLength}
+
+		if name := hostConfig.IPDetector; name != "" {
+			if detectors[name] == nil {
+				detector, err := NewDetector(cfg, name)
+				if err != nil {
+					return nil, fmt.Errorf("invalid sync host %s: %w", hostConfig.Domain, err)
+				}
+				detectors[name] = detector
+			}
+			host.Detector = detectors[name]
+		}
+
+		if hostConfig.IPv6Suffix != "" {
+			suffix, err := ip.ParseInterfaceID(hostConfig.IPv6Suffix)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sync host %s: %w", hostConfig.Domain, err)
+			}
+			host.IPv6Suffix = suffix
+		}
+
+		hosts[hostConfig.Domain] = host
+	}
+
+	return hosts, nil
+}
+
+func NewDomainSource(cfg *config.Config) (domain.DomainSource, error) {
+	switch cfg.Sync.DomainSource {
+	case "", "config":
+		return domain.NewListSource(cfg.Sync.Domains), nil
+	case "caddyfile":
+		return domain.NewCaddyfileSource(cfg.Preferences.CaddyfilePath), nil
+	case "caddy":
+		if cfg.Sources.Caddy.ConfigPath != "" {
+			return domain.NewCaddyJSONFileSource(cfg.Sources.Caddy.ConfigPath), nil
+		}
+		return domain.NewCaddyAdminSource(cfg.Sources.Caddy.AdminURL, cfg.Sources.Caddy.Timeout), nil
+	case "nginx":
+		source := domain.NewNginxSource(cfg.Sources.Nginx.ConfigPath)
+		source.IncludeWildcards = cfg.Sources.Nginx.IncludeWildcards
+		return source, nil
+	case "traefik":
+		traefik := cfg.Sources.Traefik
+		socket := ""
+		if traefik.Docker {
+			socket = traefik.DockerSocket
+			if socket == "" {
+				socket = domain.DefaultDockerSocket
+			}
+		}
+		if len(traefik.Paths) == 0 && socket == "" {
+			return nil, fmt.Errorf("the traefik domain source needs sources.traefik.paths or sources.traefik.docker")
+		}
+		return domain.NewTraefikSource(traefik.Paths, socket, traefik.Timeout), nil
+	case "kubernetes":
+		if len(cfg.Sources.Kubernetes.Paths) == 0 {
+			return nil, fmt.Errorf("the kubernetes domain source needs sources.kubernetes.paths")
+		}
+		source := domain.NewKubernetesSource(cfg.Sources.Kubernetes.Paths)
+		source.IngressClass = cfg.Sources.Kubernetes.IngressClass
+		return source, nil
+	default:
+		return nil, fmt.Errorf("unknown domain source: %s", cfg.Sync.DomainSource)
+	}
+}
+
+// NewDetector returns the detector called name, set up from the ip section
+// of cfg.
+func NewDetector(cfg *config.Config, name string) (ip.IPDetector, error) {
+	return newDetector(cfg, name, nil)
+}
+
+// newDetector builds the detector called name. building lists the composed
+// detectors whose sources are being built, to reject cycles.
+func newDetector(cfg *config.Config, name string, building []string) (ip.IPDetector, error) {
+	switch name {
+	case "interface":
+		return newInterfaceDetector(cfg.IP.Interface, ip.ClassPublic)
+	case "lan":
+		return newInterfaceDetector(cfg.IP.LAN, ip.ClassPrivate)
+	case "", "api":
+		return ip.NewAPIDetectorWithEndpoints(echoEndpoints(cfg.IP.API.IPv4), echoEndpoints(cfg.IP.API.IPv6))
+	case "consensus":
+		return newConsensusDetector(cfg, append(slices.Clip(building), name))
+	case "chain":
+		return newChainDetector(cfg, append(slices.Clip(building), name))
+	case "stun":
+		return ip.NewSTUNDetector(cfg.IP.STUN.Servers, cfg.IP.STUN.Timeout), nil
+	case "metadata":
+		return ip.NewMetadataDetector(ip.Cloud(cfg.IP.Metadata.Cloud), cfg.IP.Metadata.Timeout)
+	case "router":
+		return ip.NewRouterDetector(cfg.IP.Router.Gateway, cfg.IP.Router.Timeout), nil
+	case "command":
+		return ip.NewCommandDetector(cfg.IP.Command.IPv4, cfg.IP.Command.IPv6, cfg.IP.Command.Timeout)
+	case "dns":
+		return ip.NewDNSDetector(dnsQueries(cfg.IP.DNS.IPv4), dnsQueries(cfg.IP.DNS.IPv6), cfg.IP.DNS.Timeout)
+	default:
+		return nil, fmt.Errorf("unknown IP detector: %s", name)
+	}
+}
+
+// newInterfaceDetector returns an interface detector publishing the
+// configured address classes, or defaultClass.
+func newInterfaceDetector(interfaceConfig config.InterfaceConfig, defaultClass ip.AddressClass) (ip.IPDetector, error) {
+	detector, err := ip.NewInterfaceDetectorWithFilter(interfaceConfig.Include, interfaceConfig.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	detector.Classes = []ip.AddressClass{defaultClass}
+	if len(interfaceConfig.AddressClasses) > 0 {
+		detector.Classes = nil
+		for _, value := range interfaceConfig.AddressClasses {
+			class, err := ip.ParseAddressClass(value)
+			if err != nil {
+				return nil, err
+			}
+			detector.Classes = append(detector.Classes, class)
+		}
+	}
+
+	return detector, nil
+}
+
+func newConsensusDetector(cfg *config.Config, building []string) (ip.IPDetector, error) {
+	ipv4, err := sourceDetectors(cfg, cfg.IP.Consensus.IPv4, building)
+	if err != nil {
+		return nil, fmt.Errorf("invalid consensus IPv4 source: %w", err)
+	}
+
+	ipv6, err := sourceDetectors(cfg, cfg.IP.Consensus.IPv6, building)
+	if err != nil {
+		return nil, fmt.Errorf("invalid consensus IPv6 source: %w", err)
+	}
+
+	return ip.NewConsensusDetector(cfg.IP.Consensus.Quorum, ipv4, ipv6), nil
+}
+
+func newChainDetector(cfg *config.Config, building []string) (ip.IPDetector, error) {
+	if len(cfg.IP.Chain.IPv4) == 0 && len(cfg.IP.Chain.IPv6) == 0 {
+		return nil, fmt.Errorf("no detectors configured in ip.chain")
+	}
+
+	ipv4, err := sourceDetectors(cfg, cfg.IP.Chain.IPv4, building)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain IPv4 detector: %w", err)
+	}
+
+	ipv6, err := sourceDetectors(cfg, cfg.IP.Chain.IPv6, building)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain IPv6 detector: %w", err)
+	}
+
+	return ip.NewChainDetector(ipv4, ipv6), nil
+}
+
+// sourceDetectors builds the detectors of composed detectors. A source with
+// a URL is an API detector asking only that echo service.
+func sourceDetectors(cfg *config.Config, sources []config.DetectorSourceConfig, building []string) ([]ip.IPDetector, error) {
+	var detectors []ip.IPDetector
+
+	for _, source := range sources {
+		var detector ip.IPDetector
+		var err error
+
+		switch {
+		case source.URL != "" && source.Detector != "":
+			return nil, fmt.Errorf("source sets both detector %s and url %s", source.Detector, source.URL)
+		case source.URL != "":
+			endpoints := echoEndpoints([]config.EchoEndpointConfig{source.EchoEndpointConfig})
+			detector, err = ip.NewAPIDetectorWithEndpoints(endpoints, endpoints)
+		case slices.Contains(building, source.Detector):
+			if source.Detector == building[len(building)-1] {
+				return nil, fmt.Errorf("%s cannot be a source of itself", source.Detector)
+			}
+			return nil, fmt.Errorf("%s cannot be a source of itself through %s", source.Detector, strings.Join(building[slices.Index(building, source.Detector)+1:], ", "))
+		case source.Detector != "":
+			detector, err = newDetector(cfg, source.Detector, building)
+		default:
+			return nil, fmt.Errorf("source needs a detector or url")
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		detectors = append(detectors, detector)
+	}
+
+	return detectors, nil
+}
+
+func echoEndpoints(endpoints []config.EchoEndpointConfig) []ip.Endpoint {
+	var result []ip.Endpoint
+	for _, endpoint := range endpoints {
+		result = append(result, ip.Endpoint{
+			URL:     endpoint.URL,
+			Format:  ip.EchoFormat(endpoint.Format),
+			Field:   endpoint.Field,
+			Pattern: endpoint.Pattern,
+			Timeout: endpoint.Timeout,
+		})
+	}
+	return result
+}
+
+func dnsQueries(queries []config.DNSQuerySourceConfig) []ip.DNSQuery {
+	var result []ip.DNSQuery
+	for _, query := range queries {
+		answerType := ip.DNSAnswerType(query.Type)
+		if answerType == "" {
+			answerType = ip.DNSAnswerAddress
+		}
+		result = append(result, ip.DNSQuery{
+			Resolver: query.Resolver,
+			Name:     query.Name,
+			Type:     answerType,
+		})
+	}
+	return result
+}
+
+func ParseRecordTypes(values []string) ([]dnsset.RecordType, error) {
+	var recordTypes []dnsset.RecordType
+	for _, value := range values {
+		recordType, err := dnsset.ParseRecordType(value)
+		if err != nil {
+			return nil, err
+		}
+		recordTypes = append(recordTypes, recordType)
+	}
+	return recordTypes, nil
+}
+
+// WatchFilter selects the address events that may change the result of
+// the interface detector configured in ip.interface: public addresses on
+// the selected interfaces.
+func WatchFilter(cfg *config.Config) (func(watch.AddressEvent) bool, error) {
+	detector, err := newInterfaceDetector(cfg.IP.Interface, ip.ClassPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	relevant := detector.(*ip.InterfaceDetector).Relevant
+	return func(event watch.AddressEvent) bool {
+		return relevant(event.Interface, event.IP)
+	}, nil
+}
+
+// StateProvider wraps provider with the state file configured in the state
+// section, so records applied before with the same content are not sent to
+// the provider again. It returns provider itself when the state is disabled.
+// With refresh every record goes to the provider and the state is rewritten.
+func StateProvider(cfg *config.Config, provider dnsset.DNSProvider, refresh bool) (dnsset.DNSProvider, error) {
+	if !cfg.State.Enabled {
+		return provider, nil
+	}
+
+	path, err := config.StatePath(cfg.State.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := state.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := state.NewProvider(provider, file, cfg.State.MaxAge)
+	cached.Refresh = refresh
+	return cached, nil
+}
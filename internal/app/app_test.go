@@ -0,0 +1,194 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yy4382/dns-set/internal/config"
+	"github.com/yy4382/dns-set/pkg/dnsset"
+)
+
+func TestParseRecordTypes(t *testing.T) {
+	recordTypes, err := ParseRecordTypes([]string{"a", " AAAA "})
+	require.NoError(t, err)
+	assert.Equal(t, []dnsset.RecordType{dnsset.RecordTypeA, dnsset.RecordTypeAAAA}, recordTypes)
+
+	_, err = ParseRecordTypes([]string{"MX"})
+	assert.EqualError(t, err, "unsupported record type: MX")
+}
+
+func TestSpecFromConfig(t *testing.T) {
+	cfg := &config.Config{
+		Sync: config.SyncConfig{
+			DomainSource: "config",
+			Domains:      []string{"example.com"},
+			IPDetector:   "interface",
+			RecordTypes:  []string{"A", "AAAA"},
+			Proxied:      true,
+		},
+	}
+
+	spec, err := SpecFromConfig(cfg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com"}, spec.Domains)
+	assert.Equal(t, []dnsset.RecordType{dnsset.RecordTypeA, dnsset.RecordTypeAAAA}, spec.RecordTypes)
+	assert.Equal(t, "Network Interface", spec.Detector.Name())
+	assert.True(t, spec.Proxied)
+
+	cfg.Sync.IPDetector = "carrier-pigeon"
+	_, err = SpecFromConfig(cfg, nil)
+	assert.EqualError(t, err, "unknown IP detector: carrier-pigeon")
+
+	cfg.Sync.IPDetector = "api"
+	cfg.Sync.DomainSource = "zonefile"
+	_, err = SpecFromConfig(cfg, nil)
+	assert.EqualError(t, err, "unknown domain source: zonefile")
+}
+
+func TestNewDomainSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		sync     config.SyncConfig
+		sources  config.SourcesConfig
+		expected string
+	}{
+		{"config", config.SyncConfig{DomainSource: "config"}, config.SourcesConfig{}, "Config"},
+		{"caddy file", config.SyncConfig{DomainSource: "caddy"}, config.SourcesConfig{Caddy: config.CaddyConfig{ConfigPath: "/etc/caddy/caddy.json"}}, "Caddy config (/etc/caddy/caddy.json)"},
+		{"caddy admin", config.SyncConfig{DomainSource: "caddy"}, config.SourcesConfig{}, "Caddy admin API (http://localhost:2019)"},
+		{"nginx", config.SyncConfig{DomainSource: "nginx"}, config.SourcesConfig{}, "nginx (/etc/nginx/nginx.conf)"},
+		{"traefik", config.SyncConfig{DomainSource: "traefik"}, config.SourcesConfig{Traefik: config.TraefikConfig{Paths: []string{"/etc/traefik/dynamic"}, Docker: true}}, "Traefik (/etc/traefik/dynamic, Docker)"},
+		{"kubernetes", config.SyncConfig{DomainSource: "kubernetes"}, config.SourcesConfig{Kubernetes: config.KubernetesConfig{Paths: []string{"deploy/", "ingress.yaml"}}}, "Kubernetes (deploy/, ingress.yaml)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source, err := NewDomainSource(&config.Config{Sync: test.sync, Sources: test.sources})
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, source.Name())
+		})
+	}
+}
+
+func TestNewDomainSource_Unconfigured(t *testing.T) {
+	_, err := NewDomainSource(&config.Config{Sync: config.SyncConfig{DomainSource: "traefik"}})
+	assert.EqualError(t, err, "the traefik domain source needs sources.traefik.paths or sources.traefik.docker")
+
+	_, err = NewDomainSource(&config.Config{Sync: config.SyncConfig{DomainSource: "kubernetes"}})
+	assert.EqualError(t, err, "the kubernetes domain source needs sources.kubernetes.paths")
+}
+
+func TestNewDetector_Consensus(t *testing.T) {
+	cfg := &config.Config{
+		IP: config.IPConfig{
+			Consensus: config.ConsensusConfig{
+				Quorum: 2,
+				IPv4: []config.DetectorSourceConfig{
+					{Detector: "interface"},
+					{EchoEndpointConfig: config.EchoEndpointConfig{URL: "https://api.ipify.org"}},
+				},
+			},
+		},
+	}
+
+	detector, err := NewDetector(cfg, "consensus")
+	require.NoError(t, err)
+	assert.Equal(t, "Consensus", detector.Name())
+
+	cfg.IP.Consensus.IPv6 = []config.DetectorSourceConfig{{Detector: "consensus"}}
+	_, err = NewDetector(cfg, "consensus")
+	assert.EqualError(t, err, "invalid consensus IPv6 source: consensus cannot be a source of itself")
+
+	cfg.IP.Consensus.IPv6 = []config.DetectorSourceConfig{{}}
+	_, err = NewDetector(cfg, "consensus")
+	assert.EqualError(t, err, "invalid consensus IPv6 source: source needs a detector or url")
+}
+
+func TestNewDetector_DNS(t *testing.T) {
+	cfg := &config.Config{
+		IP: config.IPConfig{
+			DNS: config.DNSQueryConfig{
+				IPv4: []config.DNSQuerySourceConfig{{Resolver: "127.0.0.1:5353", Name: "whoami.example"}},
+			},
+		},
+	}
+
+	detector, err := NewDetector(cfg, "dns")
+	require.NoError(t, err)
+	assert.Equal(t, "DNS Query", detector.Name())
+
+	cfg.IP.DNS.IPv6 = []config.DNSQuerySourceConfig{{Resolver: "127.0.0.1", Name: "whoami.example", Type: "srv"}}
+	_, err = NewDetector(cfg, "dns")
+	assert.EqualError(t, err, `DNS query whoami.example: unknown answer type "srv"`)
+}
+
+func TestSpecFromConfig_Hosts(t *testing.T) {
+	cfg := &config.Config{
+		Sync: config.SyncConfig{
+			Domains:    []string{"router.example.com", "nas.example.com"},
+			IPDetector: "api",
+			Hosts: []config.HostConfig{
+				{Domain: "nas.example.com", IPv6Suffix: "::10"},
+				{Domain: "printer.example.com", IPv6Suffix: "52:54:00:12:34:56", IPv6PrefixLength: 56, IPDetector: "interface"},
+				{Domain: "cam.example.com", IPDetector: "interface"},
+			},
+		},
+	}
+
+	spec, err := SpecFromConfig(cfg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"router.example.com", "nas.example.com", "printer.example.com", "cam.example.com"}, spec.Domains)
+	assert.Equal(t, "::10", spec.Hosts["nas.example.com"].IPv6Suffix.String())
+	assert.Nil(t, spec.Hosts["nas.example.com"].Detector)
+	assert.Equal(t, "::5054:ff:fe12:3456", spec.Hosts["printer.example.com"].IPv6Suffix.String())
+	assert.Equal(t, 56, spec.Hosts["printer.example.com"].IPv6PrefixLength)
+	assert.Same(t, spec.Hosts["printer.example.com"].Detector, spec.Hosts["cam.example.com"].Detector)
+
+	cfg.Sync.Hosts = []config.HostConfig{{Domain: "nas.example.com", IPv6Suffix: "nas"}}
+	_, err = SpecFromConfig(cfg, nil)
+	assert.ErrorContains(t, err, "invalid sync host nas.example.com: invalid interface ID")
+
+	cfg.Sync.Hosts = []config.HostConfig{{Domain: "nas.example.com"}, {Domain: "nas.example.com"}}
+	_, err = SpecFromConfig(cfg, nil)
+	assert.EqualError(t, err, "sync host nas.example.com is listed twice")
+}
+
+func TestNewDetector_LAN(t *testing.T) {
+	cfg := &config.Config{}
+
+	detector, err := NewDetector(cfg, "lan")
+	require.NoError(t, err)
+	assert.Equal(t, "Network Interface (private)", detector.Name())
+
+	cfg.IP.Interface.AddressClasses = []string{"public", "shared"}
+	detector, err = NewDetector(cfg, "interface")
+	require.NoError(t, err)
+	assert.Equal(t, "Network Interface (public or shared)", detector.Name())
+
+	cfg.IP.Interface.AddressClasses = []string{"cgnat"}
+	_, err = NewDetector(cfg, "interface")
+	assert.EqualError(t, err, "unknown address class: cgnat")
+}
+
+func TestNewDetector_Chain(t *testing.T) {
+	cfg := &config.Config{
+		IP: config.IPConfig{
+			Chain: config.ChainConfig{
+				IPv4: []config.DetectorSourceConfig{{Detector: "metadata"}, {Detector: "api"}},
+				IPv6: []config.DetectorSourceConfig{{Detector: "interface"}, {Detector: "api"}},
+			},
+		},
+	}
+
+	detector, err := NewDetector(cfg, "chain")
+	require.NoError(t, err)
+	assert.Equal(t, "Fallback (Cloud Metadata, External API (ip.sb), Network Interface)", detector.Name())
+
+	cfg.IP.Chain.IPv6 = []config.DetectorSourceConfig{{Detector: "consensus"}}
+	cfg.IP.Consensus.IPv4 = []config.DetectorSourceConfig{{Detector: "chain"}}
+	_, err = NewDetector(cfg, "chain")
+	assert.EqualError(t, err, "invalid chain IPv6 detector: invalid consensus IPv4 source: chain cannot be a source of itself through consensus")
+
+	_, err = NewDetector(&config.Config{}, "chain")
+	assert.EqualError(t, err, "no detectors configured in ip.chain")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
@@ -12,6 +13,12 @@ import (
 type Config struct {
 	Cloudflare  CloudflareConfig  `mapstructure:"cloudflare"`
 	Preferences PreferencesConfig `mapstructure:"preferences"`
+	IP          IPConfig          `mapstructure:"ip"`
+	Sync        SyncConfig        `mapstructure:"sync"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Watch       WatchConfig       `mapstructure:"watch"`
+	State       StateConfig       `mapstructure:"state"`
+	Sources     SourcesConfig     `mapstructure:"sources"`
 }
 
 type CloudflareConfig struct {
@@ -23,6 +30,230 @@ type PreferencesConfig struct {
 	DefaultTTL    *int   `mapstructure:"default_ttl" yaml:"default_ttl"`
 }
 
+// IPConfig holds the settings of the IP detectors.
+type IPConfig struct {
+	API       APIDetectorConfig `mapstructure:"api" yaml:"api"`
+	Consensus ConsensusConfig   `mapstructure:"consensus" yaml:"consensus"`
+	STUN      STUNConfig        `mapstructure:"stun" yaml:"stun"`
+	DNS       DNSQueryConfig    `mapstructure:"dns" yaml:"dns"`
+	Interface InterfaceConfig   `mapstructure:"interface" yaml:"interface"`
+	LAN       InterfaceConfig   `mapstructure:"lan" yaml:"lan"`
+	Metadata  MetadataConfig    `mapstructure:"metadata" yaml:"metadata"`
+	Router    RouterConfig      `mapstructure:"router" yaml:"router"`
+	Command   CommandConfig     `mapstructure:"command" yaml:"command"`
+	Chain     ChainConfig       `mapstructure:"chain" yaml:"chain"`
+}
+
+// APIDetectorConfig lists the echo services asked for each family, in the
+// order they are tried. An empty list uses ip.sb.
+type APIDetectorConfig struct {
+	IPv4 []EchoEndpointConfig `mapstructure:"ipv4" yaml:"ipv4"`
+	IPv6 []EchoEndpointConfig `mapstructure:"ipv6" yaml:"ipv6"`
+}
+
+// EchoEndpointConfig is an echo service answering with the address of the
+// caller. Format is "text" (the default), "json" with the address at the
+// dot-separated Field path, or "regex" with the address in the first capture
+// group of Pattern.
+type EchoEndpointConfig struct {
+	URL     string        `mapstructure:"url" yaml:"url"`
+	Format  string        `mapstructure:"format" yaml:"format"`
+	Field   string        `mapstructure:"field" yaml:"field"`
+	Pattern string        `mapstructure:"pattern" yaml:"pattern"`
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+}
+
+// ConsensusConfig lists the sources the consensus detector asks for each
+// family. An address is accepted when Quorum sources agree; zero means a
+// majority of the sources.
+type ConsensusConfig struct {
+	Quorum int                    `mapstructure:"quorum" yaml:"quorum"`
+	IPv4   []DetectorSourceConfig `mapstructure:"ipv4" yaml:"ipv4"`
+	IPv6   []DetectorSourceConfig `mapstructure:"ipv6" yaml:"ipv6"`
+}
+
+// DetectorSourceConfig is a source of a composed detector: either the
+// detector called Detector, or the single echo service at URL.
+type DetectorSourceConfig struct {
+	Detector           string `mapstructure:"detector" yaml:"detector,omitempty"`
+	EchoEndpointConfig `mapstructure:",squash" yaml:",inline"`
+}
+
+// STUNConfig lists the STUN servers ("host:port") asked in order, each
+// with Timeout. Empty values use public servers and 3 seconds.
+type STUNConfig struct {
+	Servers []string      `mapstructure:"servers" yaml:"servers"`
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+}
+
+// DNSQueryConfig lists the "whoami" DNS queries tried for each family, in
+// order, each with Timeout. Empty values use OpenDNS, Google and 3 seconds.
+type DNSQueryConfig struct {
+	IPv4    []DNSQuerySourceConfig `mapstructure:"ipv4" yaml:"ipv4"`
+	IPv6    []DNSQuerySourceConfig `mapstructure:"ipv6" yaml:"ipv6"`
+	Timeout time.Duration          `mapstructure:"timeout" yaml:"timeout"`
+}
+
+// DNSQuerySourceConfig is a name asked at a resolver ("host" or
+// "host:port"). Type is "address" for names answering with an A/AAAA record
+// or "txt" for names answering with a TXT record.
+type DNSQuerySourceConfig struct {
+	Resolver string `mapstructure:"resolver" yaml:"resolver"`
+	Name     string `mapstructure:"name" yaml:"name"`
+	Type     string `mapstructure:"type" yaml:"type"`
+}
+
+// InterfaceConfig selects the interfaces read by the interface and lan
+// detectors. Patterns are names or globs like "eth*"; an empty Include
+// reads every interface, in the order of the include pattern they match.
+// AddressClasses lists the classes that may be published, most wanted
+// first; it defaults to public for interface and private for lan.
+type InterfaceConfig struct {
+	Include        []string `mapstructure:"include" yaml:"include"`
+	Exclude        []string `mapstructure:"exclude" yaml:"exclude"`
+	AddressClasses []string `mapstructure:"address_classes" yaml:"address_classes"`
+}
+
+// MetadataConfig configures the cloud metadata detector. An empty Cloud
+// probes all supported clouds; a zero Timeout means 2 seconds per request.
+type MetadataConfig struct {
+	Cloud   string        `mapstructure:"cloud" yaml:"cloud"`
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+}
+
+// RouterConfig configures the router detector. Gateway is where NAT-PMP
+// and PCP requests go; empty means the gateway of the default route. A zero
+// Timeout means 2 seconds per step.
+type RouterConfig struct {
+	Gateway string        `mapstructure:"gateway" yaml:"gateway"`
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+}
+
+// CommandConfig configures the command detector: the program and arguments
+// run for each family, whose first output line is the address. A single
+// string is a program without arguments. A zero Timeout means 10 seconds.
+type CommandConfig struct {
+	IPv4    []string      `mapstructure:"ipv4" yaml:"ipv4"`
+	IPv6    []string      `mapstructure:"ipv6" yaml:"ipv6"`
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+}
+
+// ChainConfig lists the detectors tried in order for each family by the
+// chain detector; the first address found is used.
+type ChainConfig struct {
+	IPv4 []DetectorSourceConfig `mapstructure:"ipv4" yaml:"ipv4"`
+	IPv6 []DetectorSourceConfig `mapstructure:"ipv6" yaml:"ipv6"`
+}
+
+// SyncConfig describes a non-interactive sync: which domains to update,
+// how to detect the addresses and how the records should look.
+type SyncConfig struct {
+	DomainSource string       `mapstructure:"domain_source" yaml:"domain_source"`
+	Domains      []string     `mapstructure:"domains" yaml:"domains"`
+	IPDetector   string       `mapstructure:"ip_detector" yaml:"ip_detector"`
+	RecordTypes  []string     `mapstructure:"record_types" yaml:"record_types"`
+	Proxied      bool         `mapstructure:"proxied" yaml:"proxied"`
+	Hosts        []HostConfig `mapstructure:"hosts" yaml:"hosts"`
+}
+
+// SourcesConfig configures the domain sources that sync.domain_source can
+// name besides the config list and the Caddyfile.
+type SourcesConfig struct {
+	Caddy      CaddyConfig      `mapstructure:"caddy" yaml:"caddy"`
+	Nginx      NginxConfig      `mapstructure:"nginx" yaml:"nginx"`
+	Traefik    TraefikConfig    `mapstructure:"traefik" yaml:"traefik"`
+	Kubernetes KubernetesConfig `mapstructure:"kubernetes" yaml:"kubernetes"`
+}
+
+// CaddyConfig configures the "caddy" domain source: the Caddy JSON config
+// at ConfigPath if set, otherwise the live config of the admin API at
+// AdminURL.
+type CaddyConfig struct {
+	ConfigPath string        `mapstructure:"config_path" yaml:"config_path"`
+	AdminURL   string        `mapstructure:"admin_url" yaml:"admin_url"`
+	Timeout    time.Duration `mapstructure:"timeout" yaml:"timeout"`
+}
+
+// NginxConfig configures the "nginx" domain source. Wildcard server names
+// are only synced with IncludeWildcards.
+type NginxConfig struct {
+	ConfigPath       string `mapstructure:"config_path" yaml:"config_path"`
+	IncludeWildcards bool   `mapstructure:"include_wildcards" yaml:"include_wildcards"`
+}
+
+// TraefikConfig configures the "traefik" domain source: the dynamic
+// configuration files or directories in Paths and, with Docker, the labels
+// of the containers behind DockerSocket.
+type TraefikConfig struct {
+	Paths        []string      `mapstructure:"paths" yaml:"paths"`
+	Docker       bool          `mapstructure:"docker" yaml:"docker"`
+	DockerSocket string        `mapstructure:"docker_socket" yaml:"docker_socket"`
+	Timeout      time.Duration `mapstructure:"timeout" yaml:"timeout"`
+}
+
+// KubernetesConfig configures the "kubernetes" domain source: the manifest
+// files or directories in Paths, optionally limited to the Ingresses of
+// IngressClass.
+type KubernetesConfig struct {
+	Paths        []string `mapstructure:"paths" yaml:"paths"`
+	IngressClass string   `mapstructure:"ingress_class" yaml:"ingress_class"`
+}
+
+// HostConfig overrides the address of one domain of the sync, for records
+// pointing at other machines. IPv6Suffix ("::10" or a MAC address for its
+// EUI-64 interface ID) replaces the host part of the detected IPv6 address
+// after IPv6PrefixLength bits (64 by default). Domains listed here are
+// synced even when the domain source does not yield them.
+type HostConfig struct {
+	Domain           string `mapstructure:"domain" yaml:"domain"`
+	IPDetector       string `mapstructure:"ip_detector" yaml:"ip_detector"`
+	IPv6Suffix       string `mapstructure:"ipv6_suffix" yaml:"ipv6_suffix"`
+	IPv6PrefixLength int    `mapstructure:"ipv6_prefix_length" yaml:"ipv6_prefix_length"`
+}
+
+// WatchConfig configures dns-set watch: it syncs Debounce after address
+// changes on the interfaces settled, and every Interval.
+type WatchConfig struct {
+	Interval time.Duration `mapstructure:"interval" yaml:"interval"`
+	Debounce time.Duration `mapstructure:"debounce" yaml:"debounce"`
+}
+
+// StateConfig configures the state file remembering the records dns-set
+// applied, so unchanged records need no provider calls. Path defaults to
+// state.json in the config directory. Entries older than MaxAge are checked
+// with the provider again.
+type StateConfig struct {
+	Enabled bool          `mapstructure:"enabled" yaml:"enabled"`
+	Path    string        `mapstructure:"path" yaml:"path"`
+	MaxAge  time.Duration `mapstructure:"max_age" yaml:"max_age"`
+}
+
+type ServerConfig struct {
+	Listen string       `mapstructure:"listen" yaml:"listen"`
+	DynDNS DynDNSConfig `mapstructure:"dyndns" yaml:"dyndns"`
+	API    APIConfig    `mapstructure:"api" yaml:"api"`
+}
+
+// APIConfig configures the JSON management API. The API is only served
+// when a token is set; clients send it as "Authorization: Bearer <token>".
+type APIConfig struct {
+	Token string `mapstructure:"token" yaml:"token"`
+}
+
+// DynDNSConfig configures the dyndns2-compatible /nic/update endpoint.
+// Hostnames is the allowlist of names clients may update; an entry of the
+// form "*.example.com" allows any subdomain of example.com.
+type DynDNSConfig struct {
+	Users     []DynDNSUser `mapstructure:"users" yaml:"users"`
+	Hostnames []string     `mapstructure:"hostnames" yaml:"hostnames"`
+	Proxied   bool         `mapstructure:"proxied" yaml:"proxied"`
+}
+
+type DynDNSUser struct {
+	Username string `mapstructure:"username" yaml:"username"`
+	Password string `mapstructure:"password" yaml:"password"`
+}
+
 func Load() (*Config, error) {
 	return LoadWithConfigPath("")
 }
@@ -50,6 +281,7 @@ func LoadWithConfigPath(configPath string) (*Config, error) {
 
 	viper.BindEnv("cloudflare.api_token", "CLOUDFLARE_API_TOKEN")
 	viper.BindEnv("preferences.caddyfile_path", "DNS_SET_CADDYFILE_PATH")
+	viper.BindEnv("server.api.token", "DNS_SET_API_TOKEN")
 
 	setDefaults()
 
@@ -103,6 +335,20 @@ func SaveToPath(config *Config, configPath string) error {
 	return nil
 }
 
+// StatePath returns the path of the state file: path if set, otherwise
+// state.json in the config directory.
+func StatePath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(configDir, "state.json"), nil
+}
+
 func getConfigDir() (string, error) {
 	if customConfigDir := os.Getenv("DNS_SET_CONFIG_DIR"); customConfigDir != "" {
 		return customConfigDir, nil
@@ -147,4 +393,12 @@ func loadEnvFiles() error {
 
 func setDefaults() {
 	viper.SetDefault("preferences.caddyfile_path", "/etc/caddy/Caddyfile")
+	viper.SetDefault("sync.domain_source", "config")
+	viper.SetDefault("sync.ip_detector", "api")
+	viper.SetDefault("sync.record_types", []string{"A"})
+	viper.SetDefault("server.listen", "127.0.0.1:8053")
+	viper.SetDefault("watch.interval", "5m")
+	viper.SetDefault("watch.debounce", "5s")
+	viper.SetDefault("state.enabled", true)
+	viper.SetDefault("state.max_age", "24h")
 }
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -168,3 +169,93 @@ preferences:
 	assert.Equal(t, "/custom/Caddyfile", config.Preferences.CaddyfilePath)
 	assert.Nil(t, config.Preferences.DefaultTTL)
 }
+
+func TestLoad_WithEchoEndpoints(t *testing.T) {
+	viper.Reset()
+
+	configContent := `ip:
+  api:
+    ipv4:
+      - url: "https://api.ipify.org?format=json"
+        format: json
+        field: ip
+        timeout: 3s
+      - url: "https://api-ipv4.ip.sb/ip"`
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	config, err := LoadWithConfigPath(configPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, []EchoEndpointConfig{
+		{URL: "https://api.ipify.org?format=json", Format: "json", Field: "ip", Timeout: 3 * time.Second},
+		{URL: "https://api-ipv4.ip.sb/ip"},
+	}, config.IP.API.IPv4)
+	assert.Empty(t, config.IP.API.IPv6)
+}
+
+func TestLoad_WithConsensusSources(t *testing.T) {
+	viper.Reset()
+
+	configContent := `ip:
+  consensus:
+    quorum: 2
+    ipv4:
+      - detector: interface
+      - url: "https://api.ipify.org?format=json"
+        format: json
+        field: ip`
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	config, err := LoadWithConfigPath(configPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, config.IP.Consensus.Quorum)
+	assert.Equal(t, []DetectorSourceConfig{
+		{Detector: "interface"},
+		{EchoEndpointConfig: EchoEndpointConfig{URL: "https://api.ipify.org?format=json", Format: "json", Field: "ip"}},
+	}, config.IP.Consensus.IPv4)
+}
+
+func TestLoad_WithCommands(t *testing.T) {
+	viper.Reset()
+
+	configContent := `ip:
+  command:
+    ipv4: /usr/local/bin/wan-ip
+    ipv6: ["birdc", "show", "route", "export", "upstream"]
+    timeout: 5s`
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	config, err := LoadWithConfigPath(configPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"/usr/local/bin/wan-ip"}, config.IP.Command.IPv4)
+	assert.Equal(t, []string{"birdc", "show", "route", "export", "upstream"}, config.IP.Command.IPv6)
+	assert.Equal(t, 5*time.Second, config.IP.Command.Timeout)
+}
+
+func TestStatePath(t *testing.T) {
+	path, err := StatePath("/var/lib/dns-set/state.json")
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/dns-set/state.json", path)
+
+	t.Setenv("DNS_SET_CONFIG_DIR", "/tmp/dns-set-config")
+	path, err = StatePath("")
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/dns-set-config/state.json", path)
+
+	viper.Reset()
+	config, err := Load()
+	require.NoError(t, err)
+	assert.True(t, config.State.Enabled)
+	assert.Equal(t, 24*time.Hour, config.State.MaxAge)
+}
@@ -22,12 +22,12 @@ func NewCloudflareProvider(apiToken string) (*CloudflareProvider, error) {
 	return &CloudflareProvider{api: api}, nil
 }
 
-func (c *CloudflareProvider) UpdateRecord(domain string, recordType RecordType, ip net.IP, ttl *int, proxied bool) error {
+func (c *CloudflareProvider) UpdateRecord(domain string, recordType RecordType, ip net.IP, ttl *int, proxied bool) (Change, error) {
 	ctx := context.Background()
 
 	zoneID, err := c.getZoneID(ctx, domain)
 	if err != nil {
-		return fmt.Errorf("failed to get zone ID for domain %s: %w", domain, err)
+		return Change{}, fmt.Errorf("failed to get zone ID for domain %s: %w", domain, err)
 	}
 
 	recordName := domain
@@ -36,7 +36,7 @@ func (c *CloudflareProvider) UpdateRecord(domain string, recordType RecordType,
 		Type: string(recordType),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to list DNS records: %w", err)
+		return Change{}, fmt.Errorf("failed to list DNS records: %w", err)
 	}
 
 	ipStr := ip.String()
@@ -53,7 +53,7 @@ func (c *CloudflareProvider) UpdateRecord(domain string, recordType RecordType,
 	}
 
 	if len(records) == 0 {
-		_, err = c.api.CreateDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.CreateDNSRecordParams{
+		created, err := c.api.CreateDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.CreateDNSRecordParams{
 			Name:    recordName,
 			Type:    string(recordType),
 			Content: ipStr,
@@ -61,17 +61,21 @@ func (c *CloudflareProvider) UpdateRecord(domain string, recordType RecordType,
 			Proxied: &proxied,
 		})
 		if err != nil {
-			return fmt.Errorf("failed to create DNS record: %w", err)
+			return Change{}, fmt.Errorf("failed to create DNS record: %w", err)
 		}
-		return nil
+		return Change{Action: ActionCreated, New: toRecord(created, zoneID)}, nil
 	}
 
+	change := Change{Action: ActionUnchanged}
 	for _, record := range records {
-		if record.Content == ipStr && *record.Proxied == proxied {
+		change.Old = append(change.Old, toRecord(record, zoneID))
+
+		if record.Content == ipStr && isProxied(record) == proxied {
+			change.New = toRecord(record, zoneID)
 			continue
 		}
 
-		_, err = c.api.UpdateDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.UpdateDNSRecordParams{
+		updated, err := c.api.UpdateDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.UpdateDNSRecordParams{
 			ID:      record.ID,
 			Name:    recordName,
 			Type:    string(recordType),
@@ -80,14 +84,27 @@ func (c *CloudflareProvider) UpdateRecord(domain string, recordType RecordType,
 			Proxied: &proxied,
 		})
 		if err != nil {
-			return fmt.Errorf("failed to update DNS record: %w", err)
+			return Change{}, fmt.Errorf("failed to update DNS record: %w", err)
 		}
+
+		change.Action = ActionUpdated
+		change.New = toRecord(updated, zoneID)
 	}
 
-	return nil
+	return change, nil
 }
 
 func (c *CloudflareProvider) ListRecords(domain string) ([]Record, error) {
+	return c.listRecords(domain, domain)
+}
+
+func (c *CloudflareProvider) ListZoneRecords(zone string) ([]Record, error) {
+	return c.listRecords(zone, "")
+}
+
+// listRecords lists the A/AAAA records of the zone of domain, limited to
+// records called name unless name is empty.
+func (c *CloudflareProvider) listRecords(domain, name string) ([]Record, error) {
 	ctx := context.Background()
 
 	zoneID, err := c.getZoneID(ctx, domain)
@@ -96,7 +113,7 @@ func (c *CloudflareProvider) ListRecords(domain string) ([]Record, error) {
 	}
 
 	cfRecords, _, err := c.api.ListDNSRecords(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.ListDNSRecordsParams{
-		Name: domain,
+		Name: name,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list DNS records: %w", err)
@@ -105,20 +122,29 @@ func (c *CloudflareProvider) ListRecords(domain string) ([]Record, error) {
 	var records []Record
 	for _, cfRecord := range cfRecords {
 		if cfRecord.Type == "A" || cfRecord.Type == "AAAA" {
-			records = append(records, Record{
-				ID:      cfRecord.ID,
-				Name:    cfRecord.Name,
-				Type:    RecordType(cfRecord.Type),
-				Content: cfRecord.Content,
-				TTL:     cfRecord.TTL,
-				Proxied: *cfRecord.Proxied,
-			})
+			records = append(records, toRecord(cfRecord, zoneID))
 		}
 	}
 
 	return records, nil
 }
 
+func toRecord(cfRecord cloudflare.DNSRecord, zoneID string) Record {
+	return Record{
+		ID:      cfRecord.ID,
+		Name:    cfRecord.Name,
+		Type:    RecordType(cfRecord.Type),
+		Content: cfRecord.Content,
+		TTL:     cfRecord.TTL,
+		Proxied: isProxied(cfRecord),
+		ZoneID:  zoneID,
+	}
+}
+
+func isProxied(cfRecord cloudflare.DNSRecord) bool {
+	return cfRecord.Proxied != nil && *cfRecord.Proxied
+}
+
 func (c *CloudflareProvider) getZoneID(ctx context.Context, domain string) (string, error) {
 	// Extract root domain from subdomain (e.g., test.yyang.dev -> yyang.dev)
 	rootDomain := extractRootDomain(domain)
@@ -10,16 +10,40 @@ const (
 )
 
 type Record struct {
-	ID      string
-	Name    string
-	Type    RecordType
-	Content string
-	TTL     int
-	Proxied bool
+	ID      string     `json:"id" yaml:"id"`
+	Name    string     `json:"name" yaml:"name"`
+	Type    RecordType `json:"type" yaml:"type"`
+	Content string     `json:"content" yaml:"content"`
+	TTL     int        `json:"ttl" yaml:"ttl"`
+	Proxied bool       `json:"proxied" yaml:"proxied"`
+	ZoneID  string     `json:"zone_id,omitempty" yaml:"zone_id,omitempty"`
+}
+
+// Action is what UpdateRecord did to bring a record to the wanted state.
+type Action string
+
+const (
+	ActionCreated   Action = "created"
+	ActionUpdated   Action = "updated"
+	ActionUnchanged Action = "unchanged"
+)
+
+// Change reports the outcome of UpdateRecord: the records that existed
+// before the call and the record as it is after the call.
+type Change struct {
+	Action Action   `json:"action" yaml:"action"`
+	Old    []Record `json:"old,omitempty" yaml:"old,omitempty"`
+	New    Record   `json:"new" yaml:"new"`
 }
 
 type DNSProvider interface {
-	UpdateRecord(domain string, recordType RecordType, ip net.IP, ttl *int, proxied bool) error
+	UpdateRecord(domain string, recordType RecordType, ip net.IP, ttl *int, proxied bool) (Change, error)
 	ListRecords(domain string) ([]Record, error)
 	Name() string
 }
+
+// ZoneLister is implemented by providers that can list all A/AAAA records
+// of a zone at once.
+type ZoneLister interface {
+	ListZoneRecords(zone string) ([]Record, error)
+}
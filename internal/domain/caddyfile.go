@@ -1,13 +1,13 @@
 package domain
 
 import (
-	"bufio"
 	"fmt"
-	"os"
-	"regexp"
 	"strings"
 )
 
+// CaddyfileSource reads the site addresses of a Caddyfile. Only the keys of
+// top-level blocks are addresses; directives and their arguments are not.
+// Wildcard sites are reported as "*.example.com".
 type CaddyfileSource struct {
 	path string
 }
@@ -17,94 +17,111 @@ func NewCaddyfileSource(path string) *CaddyfileSource {
 }
 
 func (c *CaddyfileSource) GetDomains() ([]string, error) {
-	file, err := os.Open(c.path)
+	loader := &caddyfileLoader{snippets: make(map[string][]caddyToken)}
+	tokens, err := loader.load(c.path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open Caddyfile at %s: %w", c.path, err)
+		return nil, err
 	}
-	defer file.Close()
 
-	domains := make(map[string]bool)
-	scanner := bufio.NewScanner(file)
-
-	domainRegex := regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?$`)
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+	addresses, err := caddySiteAddresses(tokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Caddyfile %s: %w", c.path, err)
+	}
 
-		if line == "" || strings.HasPrefix(line, "#") {
+	var domains []string
+	seen := make(map[string]bool)
+	for _, address := range addresses {
+		parsed, err := parseCaddyAddress(address)
+		if err != nil {
 			continue
 		}
-
-		if strings.Contains(line, "{") {
-			line = strings.Split(line, "{")[0]
-			line = strings.TrimSpace(line)
-		}
-
-		parts := strings.Fields(line)
-		if len(parts) == 0 {
+		host, ok := parsed.DomainName()
+		if !ok || seen[host] {
 			continue
 		}
+		seen[host] = true
+		domains = append(domains, host)
+	}
 
-		for _, part := range parts {
-			part = strings.TrimSpace(part)
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no valid domains found in Caddyfile")
+	}
 
-			if part == "" || strings.HasPrefix(part, ":") {
-				continue
-			}
+	return domains, nil
+}
 
-			if strings.Contains(part, ":") {
-				part = strings.Split(part, ":")[0]
-			}
+func (c *CaddyfileSource) Name() string {
+	return fmt.Sprintf("Caddyfile (%s)", c.path)
+}
 
-			if domainRegex.MatchString(part) && !strings.Contains(part, "*") && !isDirective(part) {
-				domains[part] = true
-			}
+// caddySiteAddresses returns the addresses of the site blocks: the tokens
+// of the first line of each top-level block. A leading block without keys
+// holds the global options. A Caddyfile whose first site has no braces is
+// a single site. The tokens must have their imports expanded.
+func caddySiteAddresses(tokens []caddyToken) ([]string, error) {
+	var addresses []string
+
+	for pos, first := 0, true; pos < len(tokens); first = false {
+		if tokens[pos].isClose() {
+			return nil, fmt.Errorf("%s: unexpected }", tokens[pos].position())
 		}
-	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading Caddyfile: %w", err)
-	}
+		var keys []string
+		for start := pos; pos < len(tokens) && !tokens[pos].isOpen() && (pos == start || !tokens[pos].newline); pos++ {
+			keys = append(keys, splitCaddyAddresses(tokens[pos].text)...)
+		}
 
-	result := make([]string, 0, len(domains))
-	for domain := range domains {
-		result = append(result, domain)
-	}
+		if pos == len(tokens) || !tokens[pos].isOpen() {
+			if first {
+				return append(addresses, keys...), nil
+			}
+			return nil, fmt.Errorf("%s: expected { after site addresses", tokens[pos-1].position())
+		}
 
-	if len(result) == 0 {
-		return nil, fmt.Errorf("no valid domains found in Caddyfile")
-	}
+		end, err := skipCaddyBlock(tokens, pos)
+		if err != nil {
+			return nil, err
+		}
+		pos = end
 
-	return result, nil
-}
+		if len(keys) > 0 && !isCaddySnippet(keys[0]) {
+			addresses = append(addresses, keys...)
+		}
+	}
 
-func (c *CaddyfileSource) Name() string {
-	return fmt.Sprintf("Caddyfile (%s)", c.path)
+	return addresses, nil
 }
 
-func isDirective(word string) bool {
-	commonDirectives := map[string]bool{
-		"root":           true,
-		"respond":        true,
-		"reverse_proxy":  true,
-		"proxy":          true,
-		"file_server":    true,
-		"encode":         true,
-		"header":         true,
-		"rewrite":        true,
-		"uri":            true,
-		"try_files":      true,
-		"basicauth":      true,
-		"request_header": true,
-		"import":         true,
-		"log":            true,
-		"tls":            true,
-		"backend":        true,
+// skipCaddyBlock returns the index after the block opened at pos.
+func skipCaddyBlock(tokens []caddyToken, pos int) (int, error) {
+	depth := 0
+	for i := pos; i < len(tokens); i++ {
+		switch {
+		case tokens[i].isOpen():
+			depth++
+		case tokens[i].isClose():
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
 	}
+	return 0, fmt.Errorf("%s: block is not closed", tokens[pos].position())
+}
 
-	if isDirective, exists := commonDirectives[word]; exists {
-		return isDirective
+// splitCaddyAddresses splits a token of comma-separated addresses.
+func splitCaddyAddresses(token string) []string {
+	var addresses []string
+	for _, address := range strings.Split(token, ",") {
+		if address != "" {
+			addresses = append(addresses, address)
+		}
 	}
+	return addresses
+}
 
-	return false
+// isCaddySnippet reports whether key names a snippet, (name), or a named
+// route, &(name), which are not sites.
+func isCaddySnippet(key string) bool {
+	return strings.HasSuffix(key, ")") && (strings.HasPrefix(key, "(") || strings.HasPrefix(key, "&("))
 }
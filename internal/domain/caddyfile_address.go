@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var caddyEnvRegex = regexp.MustCompile(`\{\$([A-Za-z0-9_]+)(?::([^}]*))?\}`)
+
+// caddyAddress is a parsed site address, [scheme://]host[:port][/path].
+type caddyAddress struct {
+	Scheme string
+	Host   string
+	Port   string
+	Path   string
+}
+
+// parseCaddyAddress splits a site address into its parts. The host may be
+// empty, as in ":8080".
+func parseCaddyAddress(address string) (caddyAddress, error) {
+	var parsed caddyAddress
+
+	if scheme, rest, ok := strings.Cut(address, "://"); ok {
+		parsed.Scheme = strings.ToLower(scheme)
+		address = rest
+	}
+	switch parsed.Scheme {
+	case "", "http", "https":
+	default:
+		return caddyAddress{}, fmt.Errorf("unsupported scheme %s", parsed.Scheme)
+	}
+
+	if i := strings.Index(address, "/"); i >= 0 {
+		parsed.Path = address[i:]
+		address = address[:i]
+	}
+
+	parsed.Host = address
+	if strings.HasPrefix(address, "[") || strings.Count(address, ":") == 1 {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return caddyAddress{}, fmt.Errorf("invalid host %s: %w", address, err)
+		}
+		parsed.Host, parsed.Port = host, port
+	}
+	parsed.Host = strings.ToLower(parsed.Host)
+
+	return parsed, nil
+}
+
+// DomainName returns the host as a DNS name, with a wildcard first label
+// kept as "*". It is false for hosts that are no DNS name: none, IP
+// addresses, a lone "*" and unexpanded placeholders.
+func (a caddyAddress) DomainName() (string, bool) {
+	if !isDNSName(strings.TrimPrefix(a.Host, "*.")) {
+		return "", false
+	}
+	return a.Host, true
+}
+
+// expandCaddyEnv replaces the {$NAME} and {$NAME:default} placeholders in
+// Caddyfile text with environment variables. Like Caddy, an unset variable
+// without a default expands to nothing.
+func expandCaddyEnv(text string) string {
+	if !strings.Contains(text, "{$") {
+		return text
+	}
+
+	return caddyEnvRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		match := caddyEnvRegex.FindStringSubmatch(placeholder)
+		if value, ok := os.LookupEnv(match[1]); ok {
+			return value
+		}
+		return match[2]
+	})
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCaddyAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected caddyAddress
+		domain   string
+	}{
+		{"example.com", caddyAddress{Host: "example.com"}, "example.com"},
+		{"https://App.Example.com", caddyAddress{Scheme: "https", Host: "app.example.com"}, "app.example.com"},
+		{"http://*.example.com:8080", caddyAddress{Scheme: "http", Host: "*.example.com", Port: "8080"}, "*.example.com"},
+		{"example.com/api/*", caddyAddress{Host: "example.com", Path: "/api/*"}, "example.com"},
+		{"https://example.com:8443/app", caddyAddress{Scheme: "https", Host: "example.com", Port: "8443", Path: "/app"}, "example.com"},
+		{":8080", caddyAddress{Port: "8080"}, ""},
+		{"localhost", caddyAddress{Host: "localhost"}, "localhost"},
+		{"192.0.2.1:443", caddyAddress{Host: "192.0.2.1", Port: "443"}, ""},
+		{"[2001:db8::1]:443", caddyAddress{Host: "2001:db8::1", Port: "443"}, ""},
+		{"*", caddyAddress{Host: "*"}, ""},
+		{"app.*.example.com", caddyAddress{Host: "app.*.example.com"}, ""},
+		{"{host}", caddyAddress{Host: "{host}"}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			parsed, err := parseCaddyAddress(test.address)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, parsed)
+
+			domain, ok := parsed.DomainName()
+			assert.Equal(t, test.domain != "", ok)
+			assert.Equal(t, test.domain, domain)
+		})
+	}
+}
+
+func TestParseCaddyAddress_Invalid(t *testing.T) {
+	_, err := parseCaddyAddress("ftp://example.com")
+	assert.EqualError(t, err, "unsupported scheme ftp")
+
+	_, err = parseCaddyAddress("[2001:db8::1")
+	assert.Error(t, err)
+}
+
+func TestExpandCaddyEnv(t *testing.T) {
+	t.Setenv("DNS_SET_TEST_ZONE", "example.com")
+	t.Setenv("DNS_SET_TEST_EMPTY", "")
+
+	assert.Equal(t, "app.example.com", expandCaddyEnv("app.{$DNS_SET_TEST_ZONE}"))
+	assert.Equal(t, "fallback.example.net", expandCaddyEnv("{$DNS_SET_TEST_UNSET:fallback.example.net}"))
+	assert.Equal(t, "", expandCaddyEnv("{$DNS_SET_TEST_EMPTY:default}"))
+	assert.Equal(t, "", expandCaddyEnv("{$DNS_SET_TEST_UNSET}"))
+	assert.Equal(t, "{host}", expandCaddyEnv("{host}"))
+}
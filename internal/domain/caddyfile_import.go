@@ -0,0 +1,167 @@
+package domain
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// caddyfileLoader reads a Caddyfile and expands its imports the way Caddy
+// does: "import" followed by a snippet name inserts the snippet, otherwise
+// it inserts the files matching a glob relative to the importing file.
+// Snippets are defined by top-level (name) blocks, in order. The {$NAME}
+// environment placeholders of each file are expanded before it is
+// tokenized, so a value can hold several tokens.
+type caddyfileLoader struct {
+	snippets map[string][]caddyToken
+}
+
+// load returns the tokens of the Caddyfile at path with its imports
+// expanded. stack holds the files and snippets being imported, to reject
+// cycles.
+func (l *caddyfileLoader) load(path string, stack []string) ([]caddyToken, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve Caddyfile path %s: %w", path, err)
+	}
+	if slices.Contains(stack, absolute) {
+		return nil, fmt.Errorf("import cycle in Caddyfile: %s", strings.Join(append(stack, absolute), " -> "))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Caddyfile at %s: %w", path, err)
+	}
+
+	tokens, err := lexCaddyfile(expandCaddyEnv(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Caddyfile %s: %w", path, err)
+	}
+	for i := range tokens {
+		tokens[i].file = path
+	}
+
+	return l.expand(tokens, filepath.Dir(path), append(stack, absolute))
+}
+
+// expand removes the snippet definitions from tokens and replaces the
+// imports with the tokens they name. dir is the directory imports are
+// relative to.
+func (l *caddyfileLoader) expand(tokens []caddyToken, dir string, stack []string) ([]caddyToken, error) {
+	var expanded []caddyToken
+	depth := 0
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		if depth == 0 && token.newline && !token.quoted && isCaddySnippetDefinition(token.text) &&
+			i+1 < len(tokens) && tokens[i+1].isOpen() {
+			end, err := skipCaddyBlock(tokens, i+1)
+			if err != nil {
+				return nil, err
+			}
+			l.snippets[strings.Trim(token.text, "()")] = tokens[i+2 : end-1]
+			i = end - 1
+			continue
+		}
+
+		switch {
+		case token.isOpen():
+			depth++
+		case token.isClose():
+			depth--
+		}
+
+		if token.newline && !token.quoted && token.text == "import" {
+			end := i + 1
+			for end < len(tokens) && !tokens[end].newline {
+				end++
+			}
+			if end == i+1 {
+				return nil, fmt.Errorf("%s: import needs a file pattern or snippet name", token.position())
+			}
+
+			imported, err := l.importTokens(token, tokens[i+1:end], dir, stack)
+			if err != nil {
+				return nil, err
+			}
+			if len(imported) > 0 {
+				imported[0].newline = true
+			}
+			expanded = append(expanded, imported...)
+			i = end - 1
+			continue
+		}
+
+		expanded = append(expanded, token)
+	}
+
+	return expanded, nil
+}
+
+// importTokens returns the tokens named by the arguments of an import: a
+// snippet or the files matching a glob. Further arguments replace the
+// {args[N]} placeholders of the imported tokens.
+func (l *caddyfileLoader) importTokens(directive caddyToken, args []caddyToken, dir string, stack []string) ([]caddyToken, error) {
+	name := args[0].text
+
+	var imported []caddyToken
+	if snippet, ok := l.snippets[name]; ok {
+		key := "(" + name + ")"
+		if slices.Contains(stack, key) {
+			return nil, fmt.Errorf("import cycle in Caddyfile: %s", strings.Join(append(stack, key), " -> "))
+		}
+
+		tokens, err := l.expand(slices.Clone(snippet), dir, append(stack, key))
+		if err != nil {
+			return nil, err
+		}
+		imported = tokens
+	} else {
+		pattern := name
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid import pattern %s: %w", directive.position(), name, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(name, "*?[") {
+			return nil, fmt.Errorf("%s: imported file %s does not exist", directive.position(), pattern)
+		}
+
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				continue
+			}
+
+			tokens, err := l.load(match, stack)
+			if err != nil {
+				return nil, err
+			}
+			imported = append(imported, tokens...)
+		}
+	}
+
+	if len(args) > 1 {
+		var replacements []string
+		for i, arg := range args[1:] {
+			replacements = append(replacements, "{args["+strconv.Itoa(i)+"]}", arg.text)
+		}
+		replacer := strings.NewReplacer(replacements...)
+		for i := range imported {
+			imported[i].text = replacer.Replace(imported[i].text)
+		}
+	}
+
+	return imported, nil
+}
+
+// isCaddySnippetDefinition reports whether key defines a snippet, (name).
+func isCaddySnippetDefinition(key string) bool {
+	return len(key) > 2 && strings.HasPrefix(key, "(") && strings.HasSuffix(key, ")")
+}
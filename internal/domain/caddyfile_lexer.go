@@ -0,0 +1,203 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// caddyToken is a token of a Caddyfile.
+type caddyToken struct {
+	text string
+	// file and line locate the token for error messages.
+	file string
+	line int
+	// newline reports that the token is the first of a logical line. Lines
+	// joined with a trailing backslash form one logical line.
+	newline bool
+	// quoted reports a quoted string or heredoc, which is never a brace.
+	quoted bool
+}
+
+// isOpen reports whether the token opens a block.
+func (t caddyToken) isOpen() bool {
+	return !t.quoted && t.text == "{"
+}
+
+// isClose reports whether the token closes a block.
+func (t caddyToken) isClose() bool {
+	return !t.quoted && t.text == "}"
+}
+
+// position returns the file and line of the token.
+func (t caddyToken) position() string {
+	if t.file == "" {
+		return fmt.Sprintf("line %d", t.line)
+	}
+	return fmt.Sprintf("%s:%d", t.file, t.line)
+}
+
+// lexCaddyfile splits a Caddyfile into tokens the way Caddy does: tokens are
+// separated by whitespace, "#" starts a comment at the start of a token,
+// "..." and `...` quote, and <<MARKER starts a heredoc ending at a line
+// starting with MARKER.
+func lexCaddyfile(data string) ([]caddyToken, error) {
+	var (
+		tokens  []caddyToken
+		text    strings.Builder
+		inToken bool
+		quote   rune
+		token   caddyToken
+		line    = 1
+		newline = true
+	)
+
+	flush := func() {
+		if !inToken {
+			return
+		}
+		token.text = text.String()
+		tokens = append(tokens, token)
+		text.Reset()
+		inToken = false
+		newline = false
+	}
+
+	start := func(quoted bool) {
+		inToken = true
+		token = caddyToken{line: line, newline: newline, quoted: quoted}
+	}
+
+	runes := []rune(data)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if quote != 0 {
+			switch {
+			case quote == '"' && r == '\\' && i+1 < len(runes) && runes[i+1] == '"':
+				text.WriteRune('"')
+				i++
+			case r == quote:
+				quote = 0
+				flush()
+			default:
+				if r == '\n' {
+					line++
+				}
+				text.WriteRune(r)
+			}
+			continue
+		}
+
+		if r == '\\' && continuesLine(runes, i) {
+			flush()
+			for runes[i] != '\n' {
+				i++
+			}
+			line++
+			continue
+		}
+
+		if unicode.IsSpace(r) {
+			flush()
+			if r == '\n' {
+				line++
+				newline = true
+			}
+			continue
+		}
+
+		if !inToken {
+			switch {
+			case r == '#':
+				for i+1 < len(runes) && runes[i+1] != '\n' {
+					i++
+				}
+				continue
+			case r == '"' || r == '`':
+				start(true)
+				quote = r
+				continue
+			case r == '<' && i+1 < len(runes) && runes[i+1] == '<':
+				if marker, end := heredocMarker(runes, i+2); marker != "" {
+					start(true)
+					body, next, lines, ok := heredocBody(runes, end, marker)
+					if !ok {
+						return nil, fmt.Errorf("line %d: heredoc %s is not terminated", line, marker)
+					}
+					text.WriteString(body)
+					line += lines
+					flush()
+					i = next - 1
+					continue
+				}
+			}
+			start(false)
+		}
+
+		text.WriteRune(r)
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("line %d: quoted string is not terminated", token.line)
+	}
+	flush()
+
+	return tokens, nil
+}
+
+// continuesLine reports whether the backslash at i is the last character of
+// its line.
+func continuesLine(runes []rune, i int) bool {
+	i++
+	if i < len(runes) && runes[i] == '\r' {
+		i++
+	}
+	return i < len(runes) && runes[i] == '\n'
+}
+
+// heredocMarker reads the marker of a heredoc starting at i, which must be
+// followed by the end of the line. It returns the index of the newline.
+func heredocMarker(runes []rune, i int) (string, int) {
+	start := i
+	for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '-') {
+		i++
+	}
+	marker := string(runes[start:i])
+	if i < len(runes) && runes[i] == '\r' {
+		i++
+	}
+	if marker == "" || i >= len(runes) || runes[i] != '\n' {
+		return "", 0
+	}
+	return marker, i
+}
+
+// heredocBody reads the lines after the newline at i up to the line
+// starting with marker, which may be followed by more tokens. The
+// indentation of the closing marker is removed from every line, as Caddy
+// does. It returns the body, the index after the closing marker and the
+// number of newlines consumed.
+func heredocBody(runes []rune, i int, marker string) (string, int, int, bool) {
+	var lines []string
+	for consumed := 1; i < len(runes); consumed++ {
+		end := i + 1
+		for end < len(runes) && runes[end] != '\n' {
+			end++
+		}
+		current := strings.TrimSuffix(string(runes[i+1:end]), "\r")
+
+		trimmed := strings.TrimLeft(current, " \t")
+		if rest, ok := strings.CutPrefix(trimmed, marker); ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			indent := current[:len(current)-len(trimmed)]
+			for j, l := range lines {
+				lines[j] = strings.TrimPrefix(l, indent)
+			}
+			return strings.Join(lines, "\n"), i + 1 + len([]rune(indent)) + len([]rune(marker)), consumed, true
+		}
+
+		lines = append(lines, current)
+		i = end
+	}
+	return "", 0, 0, false
+}
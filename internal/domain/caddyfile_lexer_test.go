@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLexCaddyfile(t *testing.T) {
+	tokens, err := lexCaddyfile("example.com { # comment\n" +
+		"\trespond \"a \\\"quoted\\\" {\" `raw\ntext` \\\n" +
+		"\t\t200\n" +
+		"\trespond <<EOF\n" +
+		"\t\tline one\n" +
+		"\t\t  line two\n" +
+		"\t\tEOF\n" +
+		"}\n")
+	require.NoError(t, err)
+
+	expected := []caddyToken{
+		{text: "example.com", line: 1, newline: true},
+		{text: "{", line: 1},
+		{text: "respond", line: 2, newline: true},
+		{text: `a "quoted" {`, line: 2, quoted: true},
+		{text: "raw\ntext", line: 2, quoted: true},
+		{text: "200", line: 4},
+		{text: "respond", line: 5, newline: true},
+		{text: "line one\n  line two", line: 5, quoted: true},
+		{text: "}", line: 9, newline: true},
+	}
+	assert.Equal(t, expected, tokens)
+	assert.True(t, tokens[1].isOpen())
+	assert.False(t, tokens[3].isOpen())
+	assert.True(t, tokens[8].isClose())
+}
+
+func TestLexCaddyfile_Errors(t *testing.T) {
+	_, err := lexCaddyfile("example.com {\n\trespond \"open\n}\n")
+	assert.EqualError(t, err, "line 2: quoted string is not terminated")
+
+	_, err = lexCaddyfile("example.com {\n\trespond <<END\n\ttext\n}\n")
+	assert.EqualError(t, err, "line 2: heredoc END is not terminated")
+}
@@ -3,6 +3,7 @@ package domain
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -25,7 +26,7 @@ func TestCaddyfileSource_GetDomains(t *testing.T) {
 api.example.com {
 	reverse_proxy localhost:3000
 }`,
-			expected:    []string{"example.com", "api.example.com", "localhost"},
+			expected:    []string{"example.com", "api.example.com"},
 			expectError: false,
 		},
 		{
@@ -37,7 +38,7 @@ api.example.com {
 api.example.com:443 {
 	reverse_proxy localhost:3000
 }`,
-			expected:    []string{"example.com", "api.example.com", "localhost"},
+			expected:    []string{"example.com", "api.example.com"},
 			expectError: false,
 		},
 		{
@@ -61,6 +62,78 @@ sub.example.org {
 			expected:    []string{"example.com", "localhost", "sub.example.org"},
 			expectError: false,
 		},
+		{
+			name: "directives and nested blocks",
+			content: `{
+	email admin@example.com
+}
+
+example.com {
+	php_fastcgi unix//run/php/php-fpm.sock
+	redir /old new.example.net
+	@api {
+		path /api/*
+		host internal.example.net
+	}
+	handle @api {
+		reverse_proxy backend.example.net:8080
+	}
+	respond "{ not a block"
+}`,
+			expected:    []string{"example.com"},
+			expectError: false,
+		},
+		{
+			name: "comma separated addresses",
+			content: `example.com, www.example.com api.example.com,docs.example.com {
+	file_server
+}`,
+			expected:    []string{"example.com", "www.example.com", "api.example.com", "docs.example.com"},
+			expectError: false,
+		},
+		{
+			name: "heredocs and line continuations",
+			content: `example.com \
+	www.example.com {
+	respond <<HTML
+		<html>
+		not.a.site {
+		</html>
+		HTML 200
+}
+
+other.example.com {
+	respond ok
+}`,
+			expected:    []string{"example.com", "www.example.com", "other.example.com"},
+			expectError: false,
+		},
+		{
+			name: "single site without braces",
+			content: `example.com
+reverse_proxy localhost:3000`,
+			expected:    []string{"example.com"},
+			expectError: false,
+		},
+		{
+			name: "snippets are not sites",
+			content: `(common) {
+	encode gzip
+}
+
+example.com {
+	import common
+}`,
+			expected:    []string{"example.com"},
+			expectError: false,
+		},
+		{
+			name: "unclosed block",
+			content: `example.com {
+	respond ok`,
+			expected:    nil,
+			expectError: true,
+		},
 		{
 			name: "no valid domains",
 			content: `:8080 {
@@ -96,6 +169,48 @@ sub.example.org {
 	}
 }
 
+func TestCaddyfileSource_AddressesAndPlaceholders(t *testing.T) {
+	t.Setenv("DNS_SET_TEST_DOMAIN", "site.example.com")
+	t.Setenv("DNS_SET_TEST_ZONE", "example.org")
+	t.Setenv("DNS_SET_TEST_ADDRESSES", "a.example.com, b.example.com")
+
+	dir := writeTestFiles(t, map[string]string{
+		"Caddyfile": `https://app.example.com, http://*.example.com:8080 {
+	respond ok
+}
+
+{$DNS_SET_TEST_DOMAIN} app.{$DNS_SET_TEST_ZONE} {
+	respond ok
+}
+
+{$DNS_SET_TEST_UNSET:default.example.net}/api/* {
+	respond ok
+}
+
+{$DNS_SET_TEST_ADDRESSES} {
+	respond ok
+}
+
+import {$DNS_SET_TEST_SITES:sites}/*`,
+		"sites/extra": `HTTPS://Extra.Example.com:443 {
+	respond ok
+}`,
+	})
+
+	domains, err := NewCaddyfileSource(filepath.Join(dir, "Caddyfile")).GetDomains()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"app.example.com",
+		"*.example.com",
+		"site.example.com",
+		"app.example.org",
+		"default.example.net",
+		"a.example.com",
+		"b.example.com",
+		"extra.example.com",
+	}, domains)
+}
+
 func TestCaddyfileSource_GetDomains_FileNotFound(t *testing.T) {
 	source := NewCaddyfileSource("/nonexistent/Caddyfile")
 	_, err := source.GetDomains()
@@ -108,3 +223,120 @@ func TestCaddyfileSourceName(t *testing.T) {
 	source := NewCaddyfileSource(path)
 	assert.Equal(t, "Caddyfile (/etc/caddy/Caddyfile)", source.Name())
 }
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestCaddyfileSource_Imports(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"Caddyfile": `{
+	email admin@example.com
+}
+
+import snippets/*.caddy
+import sites/*.caddy
+
+main.example.com {
+	import proxy main-backend:8080
+}`,
+		"snippets/common.caddy": `(common) {
+	encode gzip
+	log
+}
+
+(proxy) {
+	import common
+	reverse_proxy {args[0]}
+}`,
+		"sites/app.caddy": `app.example.com {
+	import proxy app:3000
+}`,
+		"sites/blog.caddy": `blog.example.com, www.blog.example.com {
+	import common
+	root * /srv/blog
+	file_server
+}`,
+		"sites/notes.txt": `ignored.example.com {
+}`,
+	})
+
+	source := NewCaddyfileSource(filepath.Join(dir, "Caddyfile"))
+	domains, err := source.GetDomains()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.example.com", "blog.example.com", "www.blog.example.com", "main.example.com"}, domains)
+}
+
+func TestCaddyfileSource_ImportInsideSite(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"Caddyfile": `example.com {
+	import /dev/null
+	import handlers/*
+}`,
+		"handlers/api": `handle /api/* {
+	reverse_proxy api.internal.example.com:8080
+}`,
+	})
+
+	domains, err := NewCaddyfileSource(filepath.Join(dir, "Caddyfile")).GetDomains()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com"}, domains)
+}
+
+func TestCaddyfileSource_ImportErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		contains string
+	}{
+		{
+			name: "file cycle",
+			files: map[string]string{
+				"Caddyfile": "import a.caddy\n",
+				"a.caddy":   "import b.caddy\n",
+				"b.caddy":   "import a.caddy\n",
+			},
+			contains: "import cycle in Caddyfile",
+		},
+		{
+			name: "self import through glob",
+			files: map[string]string{
+				"Caddyfile": "import *\n",
+			},
+			contains: "import cycle in Caddyfile",
+		},
+		{
+			name: "snippet cycle",
+			files: map[string]string{
+				"Caddyfile": "(a) {\n\timport b\n}\n(b) {\n\timport a\n}\nexample.com {\n\timport a\n}\n",
+			},
+			contains: "import cycle in Caddyfile: " + "%s -> (a) -> (b) -> (a)",
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"Caddyfile": "import missing.caddy\n",
+			},
+			contains: "missing.caddy does not exist",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeTestFiles(t, test.files)
+			path := filepath.Join(dir, "Caddyfile")
+
+			_, err := NewCaddyfileSource(path).GetDomains()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), strings.ReplaceAll(test.contains, "%s", path))
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultCaddyAdminURL     = "http://localhost:2019"
+	defaultCaddyAdminTimeout = 5 * time.Second
+)
+
+// caddyJSONConfig is the part of a Caddy JSON config naming hosts.
+type caddyJSONConfig struct {
+	Apps struct {
+		HTTP struct {
+			Servers map[string]struct {
+				Routes []struct {
+					Match []struct {
+						Host []string `json:"host"`
+					} `json:"match"`
+				} `json:"routes"`
+			} `json:"servers"`
+		} `json:"http"`
+		TLS struct {
+			Automation struct {
+				Policies []struct {
+					Subjects []string `json:"subjects"`
+				} `json:"policies"`
+			} `json:"automation"`
+		} `json:"tls"`
+	} `json:"apps"`
+}
+
+// CaddyJSONSource reads the hosts of a Caddy JSON config, either from a
+// file or from the admin API of a running Caddy. The hosts are those of the
+// host matchers of the HTTP server routes and the subjects of the TLS
+// automation policies. Wildcard hosts are reported as "*.example.com".
+type CaddyJSONSource struct {
+	path     string
+	adminURL string
+	client   *http.Client
+}
+
+// NewCaddyJSONFileSource reads the Caddy JSON config at path.
+func NewCaddyJSONFileSource(path string) *CaddyJSONSource {
+	return &CaddyJSONSource{path: path}
+}
+
+// NewCaddyAdminSource reads the live config from the Caddy admin API at
+// adminURL, DefaultCaddyAdminURL if empty.
+func NewCaddyAdminSource(adminURL string, timeout time.Duration) *CaddyJSONSource {
+	if adminURL == "" {
+		adminURL = DefaultCaddyAdminURL
+	}
+	if timeout <= 0 {
+		timeout = defaultCaddyAdminTimeout
+	}
+	return &CaddyJSONSource{
+		adminURL: strings.TrimSuffix(adminURL, "/"),
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *CaddyJSONSource) GetDomains() ([]string, error) {
+	data, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var config caddyJSONConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse Caddy config: %w", err)
+	}
+
+	var hosts []string
+	servers := make([]string, 0, len(config.Apps.HTTP.Servers))
+	for name := range config.Apps.HTTP.Servers {
+		servers = append(servers, name)
+	}
+	slices.Sort(servers)
+	for _, name := range servers {
+		for _, route := range config.Apps.HTTP.Servers[name].Routes {
+			for _, match := range route.Match {
+				hosts = append(hosts, match.Host...)
+			}
+		}
+	}
+	for _, policy := range config.Apps.TLS.Automation.Policies {
+		hosts = append(hosts, policy.Subjects...)
+	}
+
+	var domains []string
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		domain, ok := caddyAddress{Host: strings.ToLower(host)}.DomainName()
+		if !ok || seen[domain] {
+			continue
+		}
+		seen[domain] = true
+		domains = append(domains, domain)
+	}
+
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no valid domains found in Caddy config")
+	}
+
+	return domains, nil
+}
+
+func (c *CaddyJSONSource) read() ([]byte, error) {
+	if c.adminURL == "" {
+		data, err := os.ReadFile(c.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Caddy config at %s: %w", c.path, err)
+		}
+		return data, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.client.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.adminURL+"/config/", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Caddy admin API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Caddy admin API response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Caddy admin API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	return data, nil
+}
+
+func (c *CaddyJSONSource) Name() string {
+	if c.adminURL != "" {
+		return fmt.Sprintf("Caddy admin API (%s)", c.adminURL)
+	}
+	return fmt.Sprintf("Caddy config (%s)", c.path)
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const caddyJSONSample = `{
+	"admin": {"listen": "localhost:2019"},
+	"apps": {
+		"http": {
+			"servers": {
+				"srv1": {
+					"listen": [":8443"],
+					"routes": [
+						{"match": [{"host": ["internal.example.net"]}]}
+					]
+				},
+				"srv0": {
+					"listen": [":443"],
+					"routes": [
+						{
+							"match": [{"host": ["example.com", "www.example.com"]}],
+							"handle": [{"handler": "subroute", "routes": [
+								{"match": [{"host": ["nested.example.com"]}]}
+							]}]
+						},
+						{"match": [{"host": ["*.Example.org"]}, {"path": ["/api/*"]}]},
+						{"match": [{"host": ["{http.request.host}", "192.0.2.1"]}]},
+						{"handle": [{"handler": "static_response"}]}
+					]
+				}
+			}
+		},
+		"tls": {
+			"automation": {
+				"policies": [
+					{"subjects": ["example.com", "mail.example.com"]},
+					{"issuers": [{"module": "acme"}]}
+				]
+			}
+		}
+	}
+}`
+
+var caddyJSONDomains = []string{"example.com", "www.example.com", "*.example.org", "internal.example.net", "mail.example.com"}
+
+func TestCaddyJSONSource_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "caddy.json")
+	require.NoError(t, os.WriteFile(path, []byte(caddyJSONSample), 0644))
+
+	source := NewCaddyJSONFileSource(path)
+	domains, err := source.GetDomains()
+	require.NoError(t, err)
+	assert.Equal(t, caddyJSONDomains, domains)
+	assert.Equal(t, "Caddy config ("+path+")", source.Name())
+}
+
+func TestCaddyJSONSource_AdminAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/config/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(caddyJSONSample))
+	}))
+	defer server.Close()
+
+	source := NewCaddyAdminSource(server.URL+"/", time.Second)
+	domains, err := source.GetDomains()
+	require.NoError(t, err)
+	assert.Equal(t, caddyJSONDomains, domains)
+	assert.Equal(t, "Caddy admin API ("+server.URL+")", source.Name())
+}
+
+func TestCaddyJSONSource_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("null\n"))
+	}))
+	defer server.Close()
+
+	_, err := NewCaddyAdminSource(server.URL, time.Second).GetDomains()
+	assert.EqualError(t, err, "no valid domains found in Caddy config")
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"forbidden"}`, http.StatusForbidden)
+	}))
+	defer failing.Close()
+
+	_, err = NewCaddyAdminSource(failing.URL, time.Second).GetDomains()
+	assert.EqualError(t, err, `Caddy admin API returned status 403: {"error":"forbidden"}`)
+
+	_, err = NewCaddyJSONFileSource("/nonexistent/caddy.json").GetDomains()
+	assert.ErrorContains(t, err, "failed to read Caddy config")
+
+	path := filepath.Join(t.TempDir(), "caddy.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	_, err = NewCaddyJSONFileSource(path).GetDomains()
+	assert.ErrorContains(t, err, "failed to parse Caddy config")
+}
+
+func TestNewCaddyAdminSource_Defaults(t *testing.T) {
+	source := NewCaddyAdminSource("", 0)
+	assert.Equal(t, "Caddy admin API (http://localhost:2019)", source.Name())
+	assert.Equal(t, defaultCaddyAdminTimeout, source.client.Timeout)
+}
@@ -0,0 +1,207 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ingressClassAnnotation is the annotation naming the class of an Ingress
+// before spec.ingressClassName existed.
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+// kubernetesHeader identifies the type of a manifest document. Only the
+// documents of the kinds a source reads are decoded further, so custom
+// resources with other shapes do not fail the manifest.
+type kubernetesHeader struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+}
+
+// group returns the API group of the header, "" for the core group.
+func (h kubernetesHeader) group() string {
+	group, _, found := strings.Cut(h.APIVersion, "/")
+	if !found {
+		return ""
+	}
+	return group
+}
+
+// kubernetesObject is the part of a manifest naming hosts: Ingress rules
+// and TLS hosts, and HTTPRoute hostnames.
+type kubernetesObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+	Spec struct {
+		IngressClassName string `yaml:"ingressClassName"`
+		Rules            []struct {
+			Host string `yaml:"host"`
+		} `yaml:"rules"`
+		TLS []struct {
+			Hosts []string `yaml:"hosts"`
+		} `yaml:"tls"`
+		Hostnames []string `yaml:"hostnames"`
+	} `yaml:"spec"`
+}
+
+// KubernetesSource reads the hosts of the Ingress and Gateway API HTTPRoute
+// objects in Kubernetes manifests: Ingress spec.rules[].host and
+// spec.tls[].hosts, and HTTPRoute spec.hostnames. Wildcard hosts are
+// reported as "*.example.com".
+type KubernetesSource struct {
+	paths []string
+
+	// IngressClass limits the Ingresses to those of this class, named by
+	// spec.ingressClassName or the kubernetes.io/ingress.class annotation.
+	// HTTPRoutes are not filtered.
+	IngressClass string
+}
+
+// NewKubernetesSource reads the multi-document YAML manifests at paths; a
+// directory stands for the YAML files below it.
+func NewKubernetesSource(paths []string) *KubernetesSource {
+	return &KubernetesSource{paths: paths}
+}
+
+func (k *KubernetesSource) GetDomains() ([]string, error) {
+	var hosts []string
+
+	for _, path := range k.paths {
+		files, err := configFiles(path, ".yml", ".yaml")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Kubernetes manifests at %s: %w", path, err)
+		}
+
+		for _, file := range files {
+			objects, err := readKubernetesObjects(file)
+			if err != nil {
+				return nil, err
+			}
+			for _, object := range objects {
+				hosts = append(hosts, k.objectHosts(object)...)
+			}
+		}
+	}
+
+	var domains []string
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		host = strings.ToLower(host)
+		if !isDNSName(strings.TrimPrefix(host, "*.")) || seen[host] {
+			continue
+		}
+		seen[host] = true
+		domains = append(domains, host)
+	}
+
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no valid domains found in Kubernetes manifests")
+	}
+
+	return domains, nil
+}
+
+func (k *KubernetesSource) Name() string {
+	return fmt.Sprintf("Kubernetes (%s)", strings.Join(k.paths, ", "))
+}
+
+// objectHosts returns the hosts of an Ingress or HTTPRoute.
+func (k *KubernetesSource) objectHosts(object kubernetesObject) []string {
+	var hosts []string
+
+	switch object.Kind {
+	case "Ingress":
+		if k.IngressClass != "" && ingressClass(object) != k.IngressClass {
+			return nil
+		}
+		for _, rule := range object.Spec.Rules {
+			hosts = append(hosts, rule.Host)
+		}
+		for _, tls := range object.Spec.TLS {
+			hosts = append(hosts, tls.Hosts...)
+		}
+	case "HTTPRoute":
+		hosts = append(hosts, object.Spec.Hostnames...)
+	}
+
+	return hosts
+}
+
+func ingressClass(object kubernetesObject) string {
+	if object.Spec.IngressClassName != "" {
+		return object.Spec.IngressClassName
+	}
+	return object.Metadata.Annotations[ingressClassAnnotation]
+}
+
+// readKubernetesObjects decodes the Ingresses and HTTPRoutes of a manifest
+// file, including those in Lists.
+func readKubernetesObjects(path string) ([]kubernetesObject, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Kubernetes manifests at %s: %w", path, err)
+	}
+
+	var objects []kubernetesObject
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err == nil {
+			objects, err = appendKubernetesObjects(objects, &document)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Kubernetes manifest %s: %w", path, err)
+		}
+	}
+
+	return objects, nil
+}
+
+// appendKubernetesObjects appends the object in node to objects if it is an
+// Ingress or HTTPRoute, or the matching items if it is a List. Other kinds
+// are skipped without being decoded.
+func appendKubernetesObjects(objects []kubernetesObject, node *yaml.Node) ([]kubernetesObject, error) {
+	var header kubernetesHeader
+	if err := node.Decode(&header); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case header.Kind == "Ingress" && header.group() == "networking.k8s.io",
+		header.Kind == "HTTPRoute" && header.group() == "gateway.networking.k8s.io":
+		var object kubernetesObject
+		if err := node.Decode(&object); err != nil {
+			return nil, err
+		}
+		return append(objects, object), nil
+	case header.Kind == "List" && header.group() == "",
+		header.Kind == "IngressList" && header.group() == "networking.k8s.io",
+		header.Kind == "HTTPRouteList" && header.group() == "gateway.networking.k8s.io":
+		var list struct {
+			Items []yaml.Node `yaml:"items"`
+		}
+		if err := node.Decode(&list); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			var err error
+			objects, err = appendKubernetesObjects(objects, &list.Items[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const kubernetesManifests = `# app manifests
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: app
+spec:
+  ingressClassName: nginx
+  tls:
+    - hosts: [app.example.com, secure.example.com]
+      secretName: app-tls
+  rules:
+    - host: app.example.com
+      http:
+        paths:
+          - path: /
+            pathType: Prefix
+            backend:
+              service: {name: app, port: {number: 80}}
+    - http:
+        paths: []
+---
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: legacy
+  annotations:
+    kubernetes.io/ingress.class: traefik
+spec:
+  rules:
+    - host: "*.Legacy.example.com"
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+spec:
+  ports: [{port: 80}]
+---
+apiVersion: traefik.io/v1alpha1
+kind: IngressRoute
+metadata:
+  name: dashboard
+spec:
+  entryPoints: [websecure]
+  routes:
+    - match: Host(` + "`traefik.example.com`" + `)
+      kind: Rule
+  tls:
+    certResolver: letsencrypt
+`
+
+func TestKubernetesSource(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"apps/app.yaml": kubernetesManifests,
+		"gateway/routes.yml": `apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: store
+spec:
+  parentRefs: [{name: public}]
+  hostnames: ["store.example.com", "*.shop.example.com"]
+  rules:
+    - matches: [{path: {type: PathPrefix, value: /}}]
+`,
+		"list.yaml": `apiVersion: v1
+kind: List
+items:
+  - apiVersion: networking.k8s.io/v1
+    kind: Ingress
+    metadata: {name: listed}
+    spec:
+      ingressClassName: nginx
+      rules: [{host: listed.example.com}]
+  - apiVersion: traefik.io/v1alpha1
+    kind: IngressRoute
+    metadata: {name: listed}
+    spec:
+      tls: {secretName: listed-tls}
+`,
+		"apps/notes.txt": "kind: Ingress\n",
+	})
+
+	source := NewKubernetesSource([]string{dir})
+	domains, err := source.GetDomains()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"app.example.com",
+		"secure.example.com",
+		"*.legacy.example.com",
+		"store.example.com",
+		"*.shop.example.com",
+		"listed.example.com",
+	}, domains)
+	assert.Equal(t, "Kubernetes ("+dir+")", source.Name())
+
+	source = NewKubernetesSource([]string{filepath.Join(dir, "apps", "app.yaml"), filepath.Join(dir, "list.yaml")})
+	source.IngressClass = "traefik"
+	domains, err = source.GetDomains()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*.legacy.example.com"}, domains)
+
+	source.IngressClass = "nginx"
+	domains, err = source.GetDomains()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.example.com", "secure.example.com", "listed.example.com"}, domains)
+}
+
+func TestKubernetesSource_Errors(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"service.yaml": "apiVersion: v1\nkind: Service\nmetadata: {name: app}\n",
+		"broken.yaml":  "kind: Ingress\nspec: [\n",
+	})
+
+	_, err := NewKubernetesSource([]string{filepath.Join(dir, "service.yaml")}).GetDomains()
+	assert.EqualError(t, err, "no valid domains found in Kubernetes manifests")
+
+	_, err = NewKubernetesSource([]string{filepath.Join(dir, "broken.yaml")}).GetDomains()
+	assert.ErrorContains(t, err, "failed to parse Kubernetes manifest")
+
+	_, err = NewKubernetesSource([]string{filepath.Join(dir, "missing.yaml")}).GetDomains()
+	assert.ErrorContains(t, err, "failed to read Kubernetes manifests")
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "fmt"
+
+// ListSource returns a fixed list of domains, e.g. from the config file.
+type ListSource struct {
+	domains []string
+}
+
+func NewListSource(domains []string) *ListSource {
+	return &ListSource{domains: domains}
+}
+
+func (l *ListSource) GetDomains() ([]string, error) {
+	var domains []string
+	for _, domain := range l.domains {
+		if !isValidDomain(domain) {
+			return nil, fmt.Errorf("invalid domain format: %s", domain)
+		}
+		domains = append(domains, domain)
+	}
+
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no domains configured")
+	}
+
+	return domains, nil
+}
+
+func (l *ListSource) Name() string {
+	return "Config"
+}
@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListSource_GetDomains(t *testing.T) {
+	domains, err := NewListSource([]string{"example.com", "api.example.com"}).GetDomains()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"example.com", "api.example.com"}, domains)
+
+	_, err = NewListSource([]string{"example.com", "not a domain"}).GetDomains()
+	assert.EqualError(t, err, "invalid domain format: not a domain")
+
+	_, err = NewListSource(nil).GetDomains()
+	assert.EqualError(t, err, "no domains configured")
+}
@@ -3,24 +3,28 @@ package domain
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
 
-type ManualSource struct{}
+// ManualSource prompts on stdin; prompts and validation messages go to out.
+type ManualSource struct {
+	out io.Writer
+}
 
-func NewManualSource() *ManualSource {
-	return &ManualSource{}
+func NewManualSource(out io.Writer) *ManualSource {
+	return &ManualSource{out: out}
 }
 
 func (m *ManualSource) GetDomains() ([]string, error) {
-	fmt.Print("Enter domains (one per line, empty line to finish):\n")
+	fmt.Fprint(m.out, "Enter domains (one per line, empty line to finish):\n")
 
 	scanner := bufio.NewScanner(os.Stdin)
 	var domains []string
 
 	for {
-		fmt.Print("> ")
+		fmt.Fprint(m.out, "> ")
 		if !scanner.Scan() {
 			break
 		}
@@ -33,7 +37,7 @@ func (m *ManualSource) GetDomains() ([]string, error) {
 		if isValidDomain(line) {
 			domains = append(domains, line)
 		} else {
-			fmt.Printf("Invalid domain format: %s\n", line)
+			fmt.Fprintf(m.out, "Invalid domain format: %s\n", line)
 		}
 	}
 
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,6 +37,6 @@ func TestIsValidDomain(t *testing.T) {
 }
 
 func TestManualSourceName(t *testing.T) {
-	source := NewManualSource()
+	source := NewManualSource(io.Discard)
 	assert.Equal(t, "Manual Input", source.Name())
 }
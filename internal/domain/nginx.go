@@ -0,0 +1,379 @@
+package domain
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const DefaultNginxConfigPath = "/etc/nginx/nginx.conf"
+
+// NginxNames are the server names of an nginx config: exact names and
+// wildcard names such as "*.example.com".
+type NginxNames struct {
+	Domains   []string
+	Wildcards []string
+}
+
+// NginxSource reads the server_name values of the server blocks of an
+// nginx config, following include directives. It skips the catch-all name
+// "_", regular expressions, names with variables and all names of default
+// servers. Wildcard names are kept apart and only returned by GetDomains
+// when IncludeWildcards is set. ".example.com" counts as both example.com
+// and *.example.com, as it does in nginx.
+type NginxSource struct {
+	path string
+
+	// IncludeWildcards makes GetDomains return the wildcard names too.
+	IncludeWildcards bool
+}
+
+func NewNginxSource(path string) *NginxSource {
+	if path == "" {
+		path = DefaultNginxConfigPath
+	}
+	return &NginxSource{path: path}
+}
+
+func (n *NginxSource) GetDomains() ([]string, error) {
+	names, err := n.Names()
+	if err != nil {
+		return nil, err
+	}
+
+	domains := names.Domains
+	if n.IncludeWildcards {
+		domains = append(domains, names.Wildcards...)
+	}
+
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no valid domains found in nginx config")
+	}
+
+	return domains, nil
+}
+
+// Names returns the exact and the wildcard server names.
+func (n *NginxSource) Names() (NginxNames, error) {
+	loader := &nginxLoader{prefix: filepath.Dir(n.path)}
+	directives, err := loader.load(n.path, nil)
+	if err != nil {
+		return NginxNames{}, err
+	}
+
+	var names NginxNames
+	seen := make(map[string]bool)
+	add := func(list *[]string, name string) {
+		if !seen[name] {
+			seen[name] = true
+			*list = append(*list, name)
+		}
+	}
+
+	for _, server := range nginxServers(directives) {
+		if isNginxDefaultServer(server) {
+			continue
+		}
+
+		for _, directive := range server.block {
+			if directive.name != "server_name" {
+				continue
+			}
+			for _, name := range directive.args {
+				name = strings.ToLower(name)
+				if domain, ok := strings.CutPrefix(name, "."); ok {
+					if isDNSName(domain) {
+						add(&names.Domains, domain)
+						add(&names.Wildcards, "*."+domain)
+					}
+					continue
+				}
+				if domain, ok := strings.CutPrefix(name, "*."); ok {
+					if isDNSName(domain) {
+						add(&names.Wildcards, "*."+domain)
+					}
+					continue
+				}
+				if isDNSName(name) {
+					add(&names.Domains, name)
+				}
+			}
+		}
+	}
+
+	return names, nil
+}
+
+func (n *NginxSource) Name() string {
+	return fmt.Sprintf("nginx (%s)", n.path)
+}
+
+// nginxServers returns the server blocks among directives and their blocks.
+func nginxServers(directives []nginxDirective) []nginxDirective {
+	var servers []nginxDirective
+	for _, directive := range directives {
+		if directive.name == "server" && directive.hasBlock {
+			servers = append(servers, directive)
+			continue
+		}
+		servers = append(servers, nginxServers(directive.block)...)
+	}
+	return servers
+}
+
+// isNginxDefaultServer reports whether a server block listens with
+// default_server, or the older "default".
+func isNginxDefaultServer(server nginxDirective) bool {
+	for _, directive := range server.block {
+		if directive.name == "listen" && (slices.Contains(directive.args, "default_server") || slices.Contains(directive.args, "default")) {
+			return true
+		}
+	}
+	return false
+}
+
+// nginxDirective is a simple directive, or a block directive when hasBlock
+// is set.
+type nginxDirective struct {
+	name     string
+	args     []string
+	block    []nginxDirective
+	hasBlock bool
+	file     string
+	line     int
+}
+
+// nginxLoader reads an nginx config and replaces include directives with
+// the directives of the files they match. Relative include paths are
+// relative to prefix, the directory of the main config, as in nginx.
+type nginxLoader struct {
+	prefix string
+}
+
+// load returns the directives of the config at path with the includes
+// expanded. stack holds the files being included, to reject cycles.
+func (l *nginxLoader) load(path string, stack []string) ([]nginxDirective, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve nginx config path %s: %w", path, err)
+	}
+	if slices.Contains(stack, absolute) {
+		return nil, fmt.Errorf("include cycle in nginx config: %s", strings.Join(append(stack, absolute), " -> "))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nginx config at %s: %w", path, err)
+	}
+
+	tokens, err := lexNginx(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse nginx config %s: %w", path, err)
+	}
+
+	directives, _, err := parseNginx(tokens, 0, false, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse nginx config %s: %w", path, err)
+	}
+
+	return l.expand(directives, append(stack, absolute))
+}
+
+func (l *nginxLoader) expand(directives []nginxDirective, stack []string) ([]nginxDirective, error) {
+	var expanded []nginxDirective
+	for _, directive := range directives {
+		if directive.name != "include" || directive.hasBlock {
+			block, err := l.expand(directive.block, stack)
+			if err != nil {
+				return nil, err
+			}
+			directive.block = block
+			expanded = append(expanded, directive)
+			continue
+		}
+
+		if len(directive.args) != 1 {
+			return nil, fmt.Errorf("%s:%d: include needs one file pattern", directive.file, directive.line)
+		}
+
+		pattern := directive.args[0]
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(l.prefix, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid include pattern %s: %w", directive.file, directive.line, directive.args[0], err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(directive.args[0], "*?[") {
+			return nil, fmt.Errorf("%s:%d: included file %s does not exist", directive.file, directive.line, pattern)
+		}
+
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				continue
+			}
+
+			included, err := l.load(match, stack)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, included...)
+		}
+	}
+	return expanded, nil
+}
+
+// nginxToken is a token of an nginx config. Unquoted ";", "{" and "}" are
+// tokens of their own.
+type nginxToken struct {
+	text   string
+	line   int
+	quoted bool
+}
+
+func (t nginxToken) is(special string) bool {
+	return !t.quoted && t.text == special
+}
+
+// lexNginx splits an nginx config into tokens: words separated by
+// whitespace, "..." and '...' strings, and ";", "{" and "}". "#" starts a
+// comment at the start of a token.
+func lexNginx(data string) ([]nginxToken, error) {
+	var (
+		tokens  []nginxToken
+		text    strings.Builder
+		inToken bool
+		token   nginxToken
+		line    = 1
+	)
+
+	flush := func() {
+		if inToken {
+			token.text = text.String()
+			tokens = append(tokens, token)
+			text.Reset()
+			inToken = false
+		}
+	}
+
+	runes := []rune(data)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == '\n':
+			flush()
+			line++
+		case unicode.IsSpace(r):
+			flush()
+		case r == ';' || r == '{' || r == '}':
+			flush()
+			tokens = append(tokens, nginxToken{text: string(r), line: line})
+		case r == '#' && !inToken:
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case (r == '"' || r == '\'') && !inToken:
+			start := line
+			var quoted strings.Builder
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == r || runes[i+1] == '\\') {
+					i++
+				}
+				if runes[i] == '\n' {
+					line++
+				}
+				quoted.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("line %d: quoted string is not terminated", start)
+			}
+			tokens = append(tokens, nginxToken{text: quoted.String(), line: start, quoted: true})
+		default:
+			if !inToken {
+				inToken = true
+				token = nginxToken{line: line}
+			}
+			if r == '\\' && i+1 < len(runes) {
+				i++
+				r = runes[i]
+			} else if end := nginxVariableEnd(runes, i); end > i {
+				// The braces of ${name} belong to the word.
+				text.WriteString(string(runes[i : end+1]))
+				i = end
+				continue
+			}
+			text.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens, nil
+}
+
+// nginxVariableEnd returns the position of the closing brace of a ${name}
+// variable starting at pos, or pos when there is none.
+func nginxVariableEnd(runes []rune, pos int) int {
+	if runes[pos] != '$' || pos+1 >= len(runes) || runes[pos+1] != '{' {
+		return pos
+	}
+	for end := pos + 2; end < len(runes); end++ {
+		switch {
+		case runes[end] == '}':
+			return end
+		case runes[end] == ';' || runes[end] == '{' || unicode.IsSpace(runes[end]):
+			return pos
+		}
+	}
+	return pos
+}
+
+// parseNginx parses the directives from pos up to the end of the tokens or,
+// in a block, the closing brace. It returns the position after them.
+func parseNginx(tokens []nginxToken, pos int, inBlock bool, file string) ([]nginxDirective, int, error) {
+	var directives []nginxDirective
+
+	for pos < len(tokens) {
+		if tokens[pos].is("}") {
+			if !inBlock {
+				return nil, 0, fmt.Errorf("line %d: unexpected }", tokens[pos].line)
+			}
+			return directives, pos + 1, nil
+		}
+		if tokens[pos].is(";") || tokens[pos].is("{") {
+			return nil, 0, fmt.Errorf("line %d: unexpected %s", tokens[pos].line, tokens[pos].text)
+		}
+
+		directive := nginxDirective{name: tokens[pos].text, file: file, line: tokens[pos].line}
+		for pos++; pos < len(tokens) && !tokens[pos].is(";") && !tokens[pos].is("{"); pos++ {
+			if tokens[pos].is("}") {
+				return nil, 0, fmt.Errorf("line %d: unexpected }", tokens[pos].line)
+			}
+			directive.args = append(directive.args, tokens[pos].text)
+		}
+		if pos == len(tokens) {
+			return nil, 0, fmt.Errorf("line %d: directive %s is not terminated by ;", directive.line, directive.name)
+		}
+
+		if tokens[pos].is("{") {
+			block, next, err := parseNginx(tokens, pos+1, true, file)
+			if err != nil {
+				return nil, 0, err
+			}
+			directive.block, directive.hasBlock, pos = block, true, next
+		} else {
+			pos++
+		}
+
+		directives = append(directives, directive)
+	}
+
+	if inBlock {
+		return nil, 0, fmt.Errorf("unexpected end of file, expecting }")
+	}
+	return directives, pos, nil
+}
@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNginxSource(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"nginx.conf": `user www-data;
+events {
+	worker_connections 768;
+}
+
+http {
+	include mime.types;
+	# server_name commented.example.com;
+
+	server {
+		listen 80 default_server;
+		server_name _ default.example.com;
+		return 444;
+	}
+
+	server {
+		listen 443 ssl;
+		server_name example.com www.example.com "quoted.example.com";
+		location / {
+			proxy_pass http://backend.example.net;
+		}
+	}
+
+	include conf.d/*.conf;
+	include sites-enabled/*;
+}`,
+		"mime.types": `types {
+	text/html html;
+}`,
+		"conf.d/wildcards.conf": `server {
+	server_name *.apps.example.com .example.org mail.*;
+}`,
+		"sites-enabled/blog": `server {
+	listen [::]:80;
+	server_name blog.example.com
+	            ~^(?<user>.+)\.users\.example\.com$
+	            $hostname
+	            192.0.2.1
+	            EXAMPLE.com;
+	set $x "{";
+}`,
+		"sites-enabled/redirect": `server {
+	listen 80;
+	server_name redirect.example.com;
+	add_header X-Served-By ${hostname};
+	return 301 https://${host}$request_uri;
+}`,
+		"sites-available/disabled": `server {
+	server_name disabled.example.com;
+}`,
+	})
+
+	source := NewNginxSource(filepath.Join(dir, "nginx.conf"))
+	names, err := source.Names()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com", "www.example.com", "quoted.example.com", "example.org", "blog.example.com", "redirect.example.com"}, names.Domains)
+	assert.Equal(t, []string{"*.apps.example.com", "*.example.org"}, names.Wildcards)
+
+	domains, err := source.GetDomains()
+	require.NoError(t, err)
+	assert.Equal(t, names.Domains, domains)
+
+	source.IncludeWildcards = true
+	domains, err = source.GetDomains()
+	require.NoError(t, err)
+	assert.Equal(t, append(names.Domains, names.Wildcards...), domains)
+
+	assert.Equal(t, "nginx ("+filepath.Join(dir, "nginx.conf")+")", source.Name())
+}
+
+func TestNginxSource_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		contains string
+	}{
+		{
+			name:     "only default server",
+			files:    map[string]string{"nginx.conf": "server {\n\tlisten 80 default_server;\n\tserver_name example.com;\n}\n"},
+			contains: "no valid domains found in nginx config",
+		},
+		{
+			name:     "include cycle",
+			files:    map[string]string{"nginx.conf": "include a.conf;\n", "a.conf": "include nginx.conf;\n"},
+			contains: "include cycle in nginx config",
+		},
+		{
+			name:     "missing include",
+			files:    map[string]string{"nginx.conf": "include missing.conf;\n"},
+			contains: "missing.conf does not exist",
+		},
+		{
+			name:     "unclosed block",
+			files:    map[string]string{"nginx.conf": "http {\n\tserver {\n\t\tserver_name example.com;\n\t}\n"},
+			contains: "unexpected end of file, expecting }",
+		},
+		{
+			name:     "missing semicolon",
+			files:    map[string]string{"nginx.conf": "server {\n\tserver_name example.com\n}\n"},
+			contains: "line 3: unexpected }",
+		},
+		{
+			name:     "unterminated string",
+			files:    map[string]string{"nginx.conf": "server {\n\tserver_name \"example.com;\n}\n"},
+			contains: "line 2: quoted string is not terminated",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeTestFiles(t, test.files)
+
+			_, err := NewNginxSource(filepath.Join(dir, "nginx.conf")).GetDomains()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.contains)
+		})
+	}
+
+	_, err := NewNginxSource("/nonexistent/nginx.conf").GetDomains()
+	assert.ErrorContains(t, err, "failed to read nginx config")
+}
+
+func TestNewNginxSource_Default(t *testing.T) {
+	assert.Equal(t, "nginx (/etc/nginx/nginx.conf)", NewNginxSource("").Name())
+}
@@ -1,6 +1,61 @@
 package domain
 
+import (
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var dnsLabelRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?$`)
+
 type DomainSource interface {
 	GetDomains() ([]string, error)
 	Name() string
 }
+
+// isDNSName reports whether name is a host name that can have DNS records.
+// Unlike isValidDomain it accepts single labels such as "localhost". It
+// rejects IP addresses, wildcards, regular expressions and placeholders.
+func isDNSName(name string) bool {
+	if name == "" || len(name) > 253 || net.ParseIP(name) != nil {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !dnsLabelRegex.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// configFiles returns path, or the files below it with one of the
+// extensions exts if it is a directory.
+func configFiles(path string, exts ...string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && slices.Contains(exts, filepath.Ext(file)) {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
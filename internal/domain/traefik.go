@@ -0,0 +1,322 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultDockerSocket   = "/var/run/docker.sock"
+	defaultTraefikTimeout = 5 * time.Second
+)
+
+// traefikRouters is the part of a Traefik dynamic configuration holding
+// router rules, as read by the file provider.
+type traefikRouters struct {
+	HTTP struct {
+		Routers map[string]struct {
+			Rule string `yaml:"rule" toml:"rule"`
+		} `yaml:"routers" toml:"routers"`
+	} `yaml:"http" toml:"http"`
+	TCP struct {
+		Routers map[string]struct {
+			Rule string `yaml:"rule" toml:"rule"`
+		} `yaml:"routers" toml:"routers"`
+	} `yaml:"tcp" toml:"tcp"`
+}
+
+// TraefikSource reads the host names of Traefik router rules, the Host and
+// HostSNI matchers, from file-provider configuration and optionally from
+// the labels of the running Docker containers.
+type TraefikSource struct {
+	paths        []string
+	dockerSocket string
+	client       *http.Client
+}
+
+// NewTraefikSource reads the dynamic configuration files at paths, YAML or
+// TOML; a directory stands for the configuration files in it. With a
+// dockerSocket the traefik.*.rule labels of the containers are read too.
+func NewTraefikSource(paths []string, dockerSocket string, timeout time.Duration) *TraefikSource {
+	if timeout <= 0 {
+		timeout = defaultTraefikTimeout
+	}
+
+	source := &TraefikSource{paths: paths, dockerSocket: dockerSocket}
+	if dockerSocket != "" {
+		source.client = &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, "unix", dockerSocket)
+				},
+			},
+		}
+	}
+	return source
+}
+
+func (t *TraefikSource) GetDomains() ([]string, error) {
+	var rules []string
+
+	for _, path := range t.paths {
+		files, err := configFiles(path, ".yml", ".yaml", ".toml")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Traefik configuration at %s: %w", path, err)
+		}
+
+		for _, file := range files {
+			fileRules, err := readTraefikRules(file)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, fileRules...)
+		}
+	}
+
+	if t.dockerSocket != "" {
+		labelRules, err := t.dockerRules()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, labelRules...)
+	}
+
+	var domains []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		hosts, err := parseTraefikRule(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, host := range hosts {
+			host = strings.ToLower(host)
+			if !isDNSName(host) || seen[host] {
+				continue
+			}
+			seen[host] = true
+			domains = append(domains, host)
+		}
+	}
+
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no valid domains found in Traefik configuration")
+	}
+
+	return domains, nil
+}
+
+func (t *TraefikSource) Name() string {
+	parts := slices.Clone(t.paths)
+	if t.dockerSocket != "" {
+		parts = append(parts, "Docker")
+	}
+	return fmt.Sprintf("Traefik (%s)", strings.Join(parts, ", "))
+}
+
+// readTraefikRules returns the router rules of a configuration file, HTTP
+// routers first, each sorted by router name.
+func readTraefikRules(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Traefik configuration at %s: %w", path, err)
+	}
+
+	var config traefikRouters
+	if filepath.Ext(path) == ".toml" {
+		err = toml.Unmarshal(data, &config)
+	} else {
+		err = yaml.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Traefik configuration %s: %w", path, err)
+	}
+
+	var rules []string
+	for _, name := range sortedKeys(config.HTTP.Routers) {
+		rules = append(rules, config.HTTP.Routers[name].Rule)
+	}
+	for _, name := range sortedKeys(config.TCP.Routers) {
+		rules = append(rules, config.TCP.Routers[name].Rule)
+	}
+	return rules, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// dockerRules returns the router rules in the labels of the running
+// containers, skipping containers labelled traefik.enable=false.
+func (t *TraefikSource) dockerRules() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.client.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker/containers/json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Docker containers: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Docker API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var containers []struct {
+		Labels map[string]string `json:"Labels"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("failed to parse Docker containers: %w", err)
+	}
+
+	var rules []string
+	for _, container := range containers {
+		if container.Labels["traefik.enable"] == "false" {
+			continue
+		}
+		for _, label := range sortedKeys(container.Labels) {
+			if isTraefikRuleLabel(label) {
+				rules = append(rules, container.Labels[label])
+			}
+		}
+	}
+	return rules, nil
+}
+
+// isTraefikRuleLabel reports whether label is traefik.http.routers.NAME.rule
+// or traefik.tcp.routers.NAME.rule.
+func isTraefikRuleLabel(label string) bool {
+	parts := strings.Split(label, ".")
+	return len(parts) == 5 && parts[0] == "traefik" && (parts[1] == "http" || parts[1] == "tcp") &&
+		parts[2] == "routers" && parts[4] == "rule"
+}
+
+// parseTraefikRule returns the arguments of the Host and HostSNI matchers
+// of a router rule such as "Host(`a.example.com`) || Host(`b.example.com`)".
+// Negated matchers and groups are skipped, as are the other matchers.
+func parseTraefikRule(rule string) ([]string, error) {
+	var (
+		hosts []string
+		// groups records for each open parenthesis whether it is negated.
+		groups  []bool
+		negated bool
+	)
+
+	for i := 0; i < len(rule); {
+		switch c := rule[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			groups = append(groups, negated)
+			negated = false
+			i++
+		case c == ')':
+			if len(groups) == 0 {
+				return nil, fmt.Errorf("invalid Traefik rule %q: unexpected )", rule)
+			}
+			groups = groups[:len(groups)-1]
+			i++
+		case strings.HasPrefix(rule[i:], "&&") || strings.HasPrefix(rule[i:], "||"):
+			i += 2
+		case c == '!':
+			negated = !negated
+			i++
+		case isTraefikIdentifier(c):
+			start := i
+			for i < len(rule) && isTraefikIdentifier(rule[i]) {
+				i++
+			}
+			matcher := rule[start:i]
+
+			args, next, err := traefikMatcherArgs(rule, i)
+			if err != nil {
+				return nil, fmt.Errorf("invalid Traefik rule %q: %s: %w", rule, matcher, err)
+			}
+			i = next
+
+			if !negated && !slices.Contains(groups, true) && (matcher == "Host" || matcher == "HostSNI") {
+				hosts = append(hosts, args...)
+			}
+			negated = false
+		default:
+			return nil, fmt.Errorf("invalid Traefik rule %q: unexpected %q", rule, c)
+		}
+	}
+
+	if len(groups) > 0 {
+		return nil, fmt.Errorf("invalid Traefik rule %q: ( is not closed", rule)
+	}
+	return hosts, nil
+}
+
+func isTraefikIdentifier(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// traefikMatcherArgs reads the parenthesized list of quoted arguments at i
+// and returns them with the index after the closing parenthesis.
+func traefikMatcherArgs(rule string, i int) ([]string, int, error) {
+	i = skipTraefikSpace(rule, i)
+	if i >= len(rule) || rule[i] != '(' {
+		return nil, 0, fmt.Errorf("expected (")
+	}
+	i = skipTraefikSpace(rule, i+1)
+
+	var args []string
+	if i < len(rule) && rule[i] == ')' {
+		return args, i + 1, nil
+	}
+
+	for {
+		if i >= len(rule) || (rule[i] != '`' && rule[i] != '"') {
+			return nil, 0, fmt.Errorf("expected quoted argument")
+		}
+		end := strings.IndexByte(rule[i+1:], rule[i])
+		if end < 0 {
+			return nil, 0, fmt.Errorf("argument is not terminated")
+		}
+		args = append(args, rule[i+1:i+1+end])
+		i = skipTraefikSpace(rule, i+end+2)
+
+		if i < len(rule) && rule[i] == ',' {
+			i = skipTraefikSpace(rule, i+1)
+			continue
+		}
+		if i < len(rule) && rule[i] == ')' {
+			return args, i + 1, nil
+		}
+		return nil, 0, fmt.Errorf("expected , or )")
+	}
+}
+
+func skipTraefikSpace(rule string, i int) int {
+	for i < len(rule) && (rule[i] == ' ' || rule[i] == '\t' || rule[i] == '\n') {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraefikRule(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected []string
+	}{
+		{"Host(`a.example.com`)", []string{"a.example.com"}},
+		{"Host(`a.example.com`) || Host(`b.example.com`)", []string{"a.example.com", "b.example.com"}},
+		{"(Host(`a.example.com`) && PathPrefix(`/api`)) || Host(\"b.example.com\")", []string{"a.example.com", "b.example.com"}},
+		{"Host(`a.example.com`, `b.example.com`)", []string{"a.example.com", "b.example.com"}},
+		{"HostSNI(`db.example.com`)", []string{"db.example.com"}},
+		{"Host(`a.example.com`) && !Host(`b.example.com`)", []string{"a.example.com"}},
+		{"!(Host(`a.example.com`) || Host(`b.example.com`)) || Host(`c.example.com`)", []string{"c.example.com"}},
+		{"HostRegexp(`^.+\\.example\\.com$`) || Header(`X-Test`, `1`)", nil},
+		{"PathPrefix(`/`)", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			hosts, err := parseTraefikRule(test.rule)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, hosts)
+		})
+	}
+}
+
+func TestParseTraefikRule_Invalid(t *testing.T) {
+	for _, rule := range []string{
+		"Host(`a.example.com`",
+		"Host(a.example.com)",
+		"Host(`a.example.com) ",
+		"(Host(`a.example.com`)",
+		"Host(`a.example.com`))",
+		"Host",
+	} {
+		_, err := parseTraefikRule(rule)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestTraefikSource_Files(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"dynamic/web.yml": `http:
+  routers:
+    web:
+      rule: "Host(` + "`app.example.com`" + `) || Host(` + "`www.example.com`" + `)"
+      service: web
+    api:
+      rule: "Host(` + "`api.example.com`" + `) && PathPrefix(` + "`/v1`" + `)"
+tcp:
+  routers:
+    postgres:
+      rule: "HostSNI(` + "`db.example.com`" + `)"
+    catchall:
+      rule: "HostSNI(` + "`*`" + `)"
+`,
+		"dynamic/nested/extra.toml": `[http.routers.blog]
+  rule = "Host(` + "`blog.example.com`" + `) || Host(` + "`APP.example.com`" + `)"
+  service = "blog"
+`,
+		"dynamic/README.md": "Host(`ignored.example.com`)",
+		"traefik.yaml": `http:
+  routers:
+    dashboard:
+      rule: Host(` + "`traefik.example.com`" + `)
+`,
+	})
+
+	source := NewTraefikSource([]string{filepath.Join(dir, "dynamic"), filepath.Join(dir, "traefik.yaml")}, "", 0)
+	domains, err := source.GetDomains()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"blog.example.com",
+		"app.example.com",
+		"api.example.com",
+		"www.example.com",
+		"db.example.com",
+		"traefik.example.com",
+	}, domains)
+	assert.Equal(t, "Traefik ("+filepath.Join(dir, "dynamic")+", "+filepath.Join(dir, "traefik.yaml")+")", source.Name())
+}
+
+func TestTraefikSource_Docker(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"Id": "1", "Labels": {
+				"traefik.enable": "true",
+				"traefik.http.routers.whoami.rule": "Host(` + "`whoami.example.com`" + `)",
+				"traefik.http.routers.whoami.entrypoints": "websecure",
+				"traefik.http.services.whoami.loadbalancer.server.port": "80"
+			}},
+			{"Id": "2", "Labels": {
+				"traefik.enable": "false",
+				"traefik.http.routers.hidden.rule": "Host(` + "`hidden.example.com`" + `)"
+			}},
+			{"Id": "3", "Labels": {
+				"traefik.tcp.routers.mqtt.rule": "HostSNI(` + "`mqtt.example.com`" + `)"
+			}},
+			{"Id": "4", "Labels": null}
+		]`))
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	source := NewTraefikSource(nil, socket, time.Second)
+	domains, err := source.GetDomains()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"whoami.example.com", "mqtt.example.com"}, domains)
+	assert.Equal(t, "Traefik (Docker)", source.Name())
+
+	_, err = NewTraefikSource(nil, filepath.Join(t.TempDir(), "missing.sock"), time.Second).GetDomains()
+	assert.ErrorContains(t, err, "failed to list Docker containers")
+}
+
+func TestTraefikSource_Errors(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"empty.yml":   "http:\n  routers: {}\n",
+		"broken.toml": "[http.routers\n",
+		"bad.yml":     "http:\n  routers:\n    web:\n      rule: Host(`a.example.com`\n",
+	})
+
+	_, err := NewTraefikSource([]string{filepath.Join(dir, "empty.yml")}, "", 0).GetDomains()
+	assert.EqualError(t, err, "no valid domains found in Traefik configuration")
+
+	_, err = NewTraefikSource([]string{filepath.Join(dir, "broken.toml")}, "", 0).GetDomains()
+	assert.ErrorContains(t, err, "failed to parse Traefik configuration")
+
+	_, err = NewTraefikSource([]string{filepath.Join(dir, "bad.yml")}, "", 0).GetDomains()
+	assert.ErrorContains(t, err, "invalid Traefik rule")
+
+	_, err = NewTraefikSource([]string{filepath.Join(dir, "missing.yml")}, "", 0).GetDomains()
+	assert.ErrorContains(t, err, "failed to read Traefik configuration")
+}
@@ -1,84 +1,301 @@
 package ip
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// EchoFormat tells how to find the address in the response of an echo
+// service.
+type EchoFormat string
+
+const (
+	// EchoFormatText responses consist of the address only.
+	EchoFormatText EchoFormat = "text"
+	// EchoFormatJSON responses are JSON objects holding the address at a
+	// dot-separated field path such as "ip" or "data.address".
+	EchoFormatJSON EchoFormat = "json"
+	// EchoFormatRegex responses are matched against a regular expression;
+	// the address is its first capture group, or the whole match.
+	EchoFormatRegex EchoFormat = "regex"
+)
+
+const defaultEchoTimeout = 10 * time.Second
+
+// maxEchoResponseSize bounds how much of a response is read, echo services
+// answer with a few bytes.
+const maxEchoResponseSize = 64 << 10
+
+// Endpoint is an IP echo service. A zero Timeout means 10 seconds.
+type Endpoint struct {
+	URL     string
+	Format  EchoFormat
+	Field   string
+	Pattern string
+	Timeout time.Duration
+}
+
+var (
+	DefaultIPv4Endpoints = []Endpoint{{URL: "https://api-ipv4.ip.sb/ip", Format: EchoFormatText}}
+	DefaultIPv6Endpoints = []Endpoint{{URL: "https://api-ipv6.ip.sb/ip", Format: EchoFormatText}}
+)
+
 type APIDetector struct {
 	client *http.Client
+	name   string
+	ipv4   []echoEndpoint
+	ipv6   []echoEndpoint
+}
+
+type echoEndpoint struct {
+	Endpoint
+	pattern *regexp.Regexp
 }
 
 func NewAPIDetector() *APIDetector {
-	return &APIDetector{
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-	}
+	detector, _ := NewAPIDetectorWithEndpoints(nil, nil)
+	return detector
 }
 
-func (a *APIDetector) GetIPv4() (net.IP, error) {
-	resp, err := a.client.Get("https://api-ipv4.ip.sb/ip")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get IPv4 from API: %w", err)
+// NewAPIDetectorWithEndpoints returns a detector that tries the endpoints of
+// a family in order until one answers with a valid address. A family without
+// endpoints uses the ip.sb default.
+func NewAPIDetectorWithEndpoints(ipv4, ipv6 []Endpoint) (*APIDetector, error) {
+	name := "External API (ip.sb)"
+	if len(ipv4) > 0 || len(ipv6) > 0 {
+		name = fmt.Sprintf("External API (%s)", strings.Join(endpointHosts(ipv4, ipv6), ", "))
+	}
+	if len(ipv4) == 0 {
+		ipv4 = DefaultIPv4Endpoints
+	}
+	if len(ipv6) == 0 {
+		ipv6 = DefaultIPv6Endpoints
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	detector := &APIDetector{
+		client: &http.Client{},
+		name:   name,
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	var err error
+	if detector.ipv4, err = compileEndpoints(ipv4); err != nil {
+		return nil, err
+	}
+	if detector.ipv6, err = compileEndpoints(ipv6); err != nil {
+		return nil, err
 	}
 
-	ipStr := strings.TrimSpace(string(body))
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
-		return nil, fmt.Errorf("invalid IP address received: %s", ipStr)
+	return detector, nil
+}
+
+func endpointHosts(endpointLists ...[]Endpoint) []string {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, endpoints := range endpointLists {
+		for _, endpoint := range endpoints {
+			host := endpoint.URL
+			if u, err := url.Parse(endpoint.URL); err == nil && u.Host != "" {
+				host = u.Hostname()
+			}
+			if !seen[host] {
+				seen[host] = true
+				hosts = append(hosts, host)
+			}
+		}
 	}
+	return hosts
+}
+
+func compileEndpoints(endpoints []Endpoint) ([]echoEndpoint, error) {
+	compiled := make([]echoEndpoint, 0, len(endpoints))
+
+	for _, endpoint := range endpoints {
+		if _, err := url.ParseRequestURI(endpoint.URL); err != nil {
+			return nil, fmt.Errorf("invalid echo endpoint URL %q: %w", endpoint.URL, err)
+		}
+
+		if endpoint.Format == "" {
+			endpoint.Format = EchoFormatText
+		}
+		if endpoint.Timeout <= 0 {
+			endpoint.Timeout = defaultEchoTimeout
+		}
 
-	if ip.To4() == nil {
-		return nil, fmt.Errorf("received non-IPv4 address: %s", ipStr)
+		e := echoEndpoint{Endpoint: endpoint}
+
+		switch endpoint.Format {
+		case EchoFormatText:
+		case EchoFormatJSON:
+			if endpoint.Field == "" {
+				return nil, fmt.Errorf("echo endpoint %s: json format needs a field", endpoint.URL)
+			}
+		case EchoFormatRegex:
+			pattern, err := regexp.Compile(endpoint.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("echo endpoint %s: invalid pattern: %w", endpoint.URL, err)
+			}
+			e.pattern = pattern
+		default:
+			return nil, fmt.Errorf("echo endpoint %s: unknown format %q", endpoint.URL, endpoint.Format)
+		}
+
+		compiled = append(compiled, e)
 	}
 
+	return compiled, nil
+}
+
+func (a *APIDetector) GetIPv4() (net.IP, error) {
+	ip, err := a.query(a.ipv4, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPv4 from API: %w", err)
+	}
 	return ip, nil
 }
 
 func (a *APIDetector) GetIPv6() (net.IP, error) {
-	resp, err := a.client.Get("https://api-ipv6.ip.sb/ip")
+	ip, err := a.query(a.ipv6, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get IPv6 from API: %w", err)
 	}
+	return ip, nil
+}
+
+func (a *APIDetector) query(endpoints []echoEndpoint, ipv6 bool) (net.IP, error) {
+	var errs []error
+
+	for _, endpoint := range endpoints {
+		ip, err := a.queryEndpoint(endpoint)
+		if err == nil {
+			err = checkFamily(ip, ipv6)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", endpoint.URL, err))
+			continue
+		}
+
+		return ip, nil
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func (a *APIDetector) queryEndpoint(endpoint echoEndpoint) (net.IP, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), endpoint.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxEchoResponseSize))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	ipStr := strings.TrimSpace(string(body))
+	return endpoint.parse(body)
+}
+
+func (e echoEndpoint) parse(body []byte) (net.IP, error) {
+	var ipStr string
+
+	switch e.Format {
+	case EchoFormatJSON:
+		var document any
+		if err := json.Unmarshal(body, &document); err != nil {
+			return nil, fmt.Errorf("invalid JSON response: %w", err)
+		}
+
+		value, err := lookupField(document, e.Field)
+		if err != nil {
+			return nil, err
+		}
+		ipStr = value
+	case EchoFormatRegex:
+		match := e.pattern.FindSubmatch(body)
+		if match == nil {
+			return nil, fmt.Errorf("response does not match %s", e.pattern)
+		}
+		ipStr = string(match[0])
+		if len(match) > 1 {
+			ipStr = string(match[1])
+		}
+	default:
+		ipStr = string(body)
+	}
+
+	ipStr = strings.TrimSpace(ipStr)
 	ip := net.ParseIP(ipStr)
 	if ip == nil {
 		return nil, fmt.Errorf("invalid IP address received: %s", ipStr)
 	}
 
-	if ip.To4() != nil {
-		return nil, fmt.Errorf("received IPv4 address instead of IPv6: %s", ipStr)
+	return ip, nil
+}
+
+// lookupField walks a dot-separated path through decoded JSON. Numeric path
+// elements index arrays.
+func lookupField(document any, path string) (string, error) {
+	current := document
+
+	for _, key := range strings.Split(path, ".") {
+		switch value := current.(type) {
+		case map[string]any:
+			next, ok := value[key]
+			if !ok {
+				return "", fmt.Errorf("field %s not found in response", path)
+			}
+			current = next
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(value) {
+				return "", fmt.Errorf("field %s not found in response", path)
+			}
+			current = value[index]
+		default:
+			return "", fmt.Errorf("field %s not found in response", path)
+		}
+	}
+
+	value, ok := current.(string)
+	if !ok {
+		return "", fmt.Errorf("field %s is not a string", path)
 	}
 
-	return ip, nil
+	return value, nil
+}
+
+func checkFamily(ip net.IP, ipv6 bool) error {
+	if !ipv6 && ip.To4() == nil {
+		return fmt.Errorf("received non-IPv4 address: %s", ip)
+	}
+	if ipv6 && ip.To4() != nil {
+		return fmt.Errorf("received IPv4 address instead of IPv6: %s", ip)
+	}
+	return nil
 }
 
 func (a *APIDetector) Name() string {
-	return "External API (ip.sb)"
+	return a.name
 }
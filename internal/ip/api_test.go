@@ -0,0 +1,183 @@
+package ip
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEchoServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "203.0.113.7")
+	})
+	mux.HandleFunc("/text6", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "2001:db8::7")
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": {"addresses": [{"ip": "203.0.113.8"}]}, "country": "NL"}`)
+	})
+	mux.HandleFunc("/trace", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "fl=123\nh=example.com\nip=203.0.113.9\nts=1700000000\n")
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+		fmt.Fprintln(w, "203.0.113.10")
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAPIDetector_Formats(t *testing.T) {
+	server := newEchoServer(t)
+
+	tests := []struct {
+		name        string
+		endpoint    Endpoint
+		expected    string
+		expectError string
+	}{
+		{
+			name:     "plain text",
+			endpoint: Endpoint{URL: server.URL + "/text"},
+			expected: "203.0.113.7",
+		},
+		{
+			name:     "json field path",
+			endpoint: Endpoint{URL: server.URL + "/json", Format: EchoFormatJSON, Field: "data.addresses.0.ip"},
+			expected: "203.0.113.8",
+		},
+		{
+			name:        "json missing field",
+			endpoint:    Endpoint{URL: server.URL + "/json", Format: EchoFormatJSON, Field: "data.ip"},
+			expectError: "field data.ip not found in response",
+		},
+		{
+			name:        "json non-string field",
+			endpoint:    Endpoint{URL: server.URL + "/json", Format: EchoFormatJSON, Field: "data"},
+			expectError: "field data is not a string",
+		},
+		{
+			name:     "regex capture group",
+			endpoint: Endpoint{URL: server.URL + "/trace", Format: EchoFormatRegex, Pattern: `(?m)^ip=(\S+)$`},
+			expected: "203.0.113.9",
+		},
+		{
+			name:        "regex without match",
+			endpoint:    Endpoint{URL: server.URL + "/trace", Format: EchoFormatRegex, Pattern: `addr=(\S+)`},
+			expectError: "response does not match",
+		},
+		{
+			name:        "wrong family",
+			endpoint:    Endpoint{URL: server.URL + "/text6"},
+			expectError: "received non-IPv4 address: 2001:db8::7",
+		},
+		{
+			name:        "error status",
+			endpoint:    Endpoint{URL: server.URL + "/broken"},
+			expectError: "API returned status 503",
+		},
+		{
+			name:        "timeout",
+			endpoint:    Endpoint{URL: server.URL + "/slow", Timeout: 50 * time.Millisecond},
+			expectError: "context deadline exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector, err := NewAPIDetectorWithEndpoints([]Endpoint{tt.endpoint}, nil)
+			require.NoError(t, err)
+
+			ip, err := detector.GetIPv4()
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ip.String())
+		})
+	}
+}
+
+func TestAPIDetector_TriesEndpointsInOrder(t *testing.T) {
+	server := newEchoServer(t)
+
+	detector, err := NewAPIDetectorWithEndpoints(
+		[]Endpoint{
+			{URL: server.URL + "/broken"},
+			{URL: server.URL + "/slow", Timeout: 50 * time.Millisecond},
+			{URL: server.URL + "/text"},
+		},
+		[]Endpoint{
+			{URL: server.URL + "/text"},
+			{URL: server.URL + "/text6"},
+		},
+	)
+	require.NoError(t, err)
+
+	ipv4, err := detector.GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.7", ipv4.String())
+
+	ipv6, err := detector.GetIPv6()
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::7", ipv6.String())
+}
+
+func TestAPIDetector_AllEndpointsFail(t *testing.T) {
+	server := newEchoServer(t)
+
+	detector, err := NewAPIDetectorWithEndpoints([]Endpoint{{URL: server.URL + "/broken"}, {URL: server.URL + "/text6"}}, nil)
+	require.NoError(t, err)
+
+	_, err = detector.GetIPv4()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), server.URL+"/broken: API returned status 503")
+	assert.Contains(t, err.Error(), server.URL+"/text6: received non-IPv4 address")
+}
+
+func TestNewAPIDetectorWithEndpoints_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint Endpoint
+	}{
+		{name: "bad url", endpoint: Endpoint{URL: "not a url"}},
+		{name: "json without field", endpoint: Endpoint{URL: "https://example.com", Format: EchoFormatJSON}},
+		{name: "bad pattern", endpoint: Endpoint{URL: "https://example.com", Format: EchoFormatRegex, Pattern: "("}},
+		{name: "unknown format", endpoint: Endpoint{URL: "https://example.com", Format: "xml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAPIDetectorWithEndpoints([]Endpoint{tt.endpoint}, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAPIDetectorName(t *testing.T) {
+	assert.Equal(t, "External API (ip.sb)", NewAPIDetector().Name())
+
+	detector, err := NewAPIDetectorWithEndpoints(
+		[]Endpoint{{URL: "https://api.ipify.org"}, {URL: "https://ifconfig.co/ip"}},
+		[]Endpoint{{URL: "https://api6.ipify.org"}, {URL: "https://ifconfig.co/ip"}},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "External API (api.ipify.org, ifconfig.co, api6.ipify.org)", detector.Name())
+}
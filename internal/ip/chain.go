@@ -0,0 +1,66 @@
+package ip
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+)
+
+// ChainDetector asks its detectors one after another and returns the first
+// address found. Each family has its own chain, so for example IPv4 can
+// come from cloud metadata while IPv6 is read from an interface. Failures
+// of detectors that are followed by another one are logged as warnings.
+type ChainDetector struct {
+	ipv4 []IPDetector
+	ipv6 []IPDetector
+}
+
+// NewChainDetector returns a detector trying ipv4 in order for IPv4 and
+// ipv6 in order for IPv6 addresses.
+func NewChainDetector(ipv4, ipv6 []IPDetector) *ChainDetector {
+	return &ChainDetector{ipv4: ipv4, ipv6: ipv6}
+}
+
+func (c *ChainDetector) GetIPv4() (net.IP, error) {
+	return c.detect("IPv4", c.ipv4, IPDetector.GetIPv4)
+}
+
+func (c *ChainDetector) GetIPv6() (net.IP, error) {
+	return c.detect("IPv6", c.ipv6, IPDetector.GetIPv6)
+}
+
+func (c *ChainDetector) detect(family string, detectors []IPDetector, get func(IPDetector) (net.IP, error)) (net.IP, error) {
+	if len(detectors) == 0 {
+		return nil, fmt.Errorf("no %s detectors configured", family)
+	}
+
+	var errs []error
+	for i, detector := range detectors {
+		ip, err := get(detector)
+		if err == nil {
+			return ip, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", detector.Name(), err))
+		if i < len(detectors)-1 {
+			log.Printf("warning: %s detector %s failed, trying %s: %v", family, detector.Name(), detectors[i+1].Name(), err)
+		}
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// Name lists the detectors of both chains.
+func (c *ChainDetector) Name() string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, detector := range append(append([]IPDetector{}, c.ipv4...), c.ipv6...) {
+		if name := detector.Name(); !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return fmt.Sprintf("Fallback (%s)", strings.Join(names, ", "))
+}
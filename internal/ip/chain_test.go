@@ -0,0 +1,42 @@
+package ip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainDetector(t *testing.T) {
+	metadata := fixedDetector{name: "Cloud Metadata"}
+	api := fixedDetector{name: "External API", ipv4: "203.0.113.7", ipv6: "2001:db8::7"}
+	iface := fixedDetector{name: "Network Interface", ipv6: "2001:db8::1"}
+
+	logs := captureLog(t)
+	detector := NewChainDetector([]IPDetector{metadata, api}, []IPDetector{iface, api})
+
+	ipv4, err := detector.GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.7", ipv4.String())
+	assert.Equal(t, "warning: IPv4 detector Cloud Metadata failed, trying External API: Cloud Metadata unavailable\n", logs.String())
+
+	logs.Reset()
+	ipv6, err := detector.GetIPv6()
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::1", ipv6.String())
+	assert.Empty(t, logs.String())
+
+	assert.Equal(t, "Fallback (Cloud Metadata, External API, Network Interface)", detector.Name())
+}
+
+func TestChainDetector_AllFail(t *testing.T) {
+	logs := captureLog(t)
+	detector := NewChainDetector([]IPDetector{fixedDetector{name: "a"}, fixedDetector{name: "b"}}, nil)
+
+	_, err := detector.GetIPv4()
+	assert.EqualError(t, err, "a: a unavailable\nb: b unavailable")
+	assert.Equal(t, "warning: IPv4 detector a failed, trying b: a unavailable\n", logs.String())
+
+	_, err = detector.GetIPv6()
+	assert.EqualError(t, err, "no IPv6 detectors configured")
+}
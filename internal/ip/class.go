@@ -0,0 +1,84 @@
+package ip
+
+import (
+	"fmt"
+	"net"
+)
+
+// AddressClass is the kind of network an address belongs to.
+type AddressClass string
+
+const (
+	ClassPublic AddressClass = "public"
+	// ClassPrivate is RFC 1918 IPv4 and unique local IPv6 (fc00::/7).
+	ClassPrivate AddressClass = "private"
+	// ClassShared is the carrier-grade NAT range 100.64.0.0/10 (RFC 6598).
+	ClassShared AddressClass = "shared"
+	// ClassDocumentation is the ranges reserved for examples, like
+	// 203.0.113.0/24 and 2001:db8::/32.
+	ClassDocumentation AddressClass = "documentation"
+	// ClassBenchmarking is 198.18.0.0/15 and 2001:2::/48 (RFC 2544, 5180).
+	ClassBenchmarking AddressClass = "benchmarking"
+	ClassLoopback     AddressClass = "loopback"
+	ClassLinkLocal    AddressClass = "link-local"
+	// ClassReserved is every other special-purpose address: unspecified,
+	// multicast, "this network", IETF protocol assignments, 240.0.0.0/4.
+	ClassReserved AddressClass = "reserved"
+)
+
+var addressClassRanges = []struct {
+	class AddressClass
+	nets  []*net.IPNet
+}{
+	{ClassShared, parseCIDRs("100.64.0.0/10")},
+	{ClassDocumentation, parseCIDRs("192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24", "2001:db8::/32", "3fff::/20")},
+	{ClassBenchmarking, parseCIDRs("198.18.0.0/15", "2001:2::/48")},
+	{ClassReserved, parseCIDRs("0.0.0.0/8", "192.0.0.0/24", "240.0.0.0/4", "2001::/23", "100::/64")},
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = ipNet
+	}
+	return nets
+}
+
+// ClassifyAddress returns the class of ip.
+func ClassifyAddress(ip net.IP) AddressClass {
+	switch {
+	case ip.IsLoopback():
+		return ClassLoopback
+	case ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast():
+		return ClassLinkLocal
+	case ip.IsPrivate():
+		return ClassPrivate
+	case ip.IsUnspecified() || ip.IsMulticast():
+		return ClassReserved
+	}
+
+	// More specific ranges come first: 2001:2::/48 lies in 2001::/23.
+	for _, r := range addressClassRanges {
+		for _, ipNet := range r.nets {
+			if ipNet.Contains(ip) {
+				return r.class
+			}
+		}
+	}
+
+	return ClassPublic
+}
+
+// ParseAddressClass parses the name of an address class.
+func ParseAddressClass(value string) (AddressClass, error) {
+	switch class := AddressClass(value); class {
+	case ClassPublic, ClassPrivate, ClassShared, ClassDocumentation, ClassBenchmarking, ClassLoopback, ClassLinkLocal, ClassReserved:
+		return class, nil
+	default:
+		return "", fmt.Errorf("unknown address class: %s", value)
+	}
+}
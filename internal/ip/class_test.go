@@ -0,0 +1,55 @@
+package ip
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyAddress(t *testing.T) {
+	tests := map[string]AddressClass{
+		"93.184.216.34":   ClassPublic,
+		"2a01:4f8::1":     ClassPublic,
+		"10.1.2.3":        ClassPrivate,
+		"172.16.0.1":      ClassPrivate,
+		"192.168.1.1":     ClassPrivate,
+		"fd00::1":         ClassPrivate,
+		"100.64.0.1":      ClassShared,
+		"100.127.255.254": ClassShared,
+		"100.128.0.1":     ClassPublic,
+		"192.0.2.1":       ClassDocumentation,
+		"198.51.100.1":    ClassDocumentation,
+		"203.0.113.1":     ClassDocumentation,
+		"2001:db8::1":     ClassDocumentation,
+		"3fff::1":         ClassDocumentation,
+		"198.18.0.1":      ClassBenchmarking,
+		"198.19.255.255":  ClassBenchmarking,
+		"2001:2::1":       ClassBenchmarking,
+		"127.0.0.1":       ClassLoopback,
+		"::1":             ClassLoopback,
+		"169.254.1.1":     ClassLinkLocal,
+		"fe80::1":         ClassLinkLocal,
+		"0.0.0.0":         ClassReserved,
+		"::":              ClassReserved,
+		"239.255.255.250": ClassReserved,
+		"192.0.0.8":       ClassReserved,
+		"240.0.0.1":       ClassReserved,
+		"255.255.255.255": ClassReserved,
+	}
+
+	for address, want := range tests {
+		t.Run(address, func(t *testing.T) {
+			assert.Equal(t, want, ClassifyAddress(net.ParseIP(address)))
+		})
+	}
+}
+
+func TestParseAddressClass(t *testing.T) {
+	class, err := ParseAddressClass("shared")
+	assert.NoError(t, err)
+	assert.Equal(t, ClassShared, class)
+
+	_, err = ParseAddressClass("cgnat")
+	assert.EqualError(t, err, "unknown address class: cgnat")
+}
@@ -0,0 +1,124 @@
+package ip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const defaultCommandTimeout = 10 * time.Second
+
+// maxCommandStderr bounds how much of the error output of a failed command
+// ends up in the error message.
+const maxCommandStderr = 512
+
+// CommandDetector runs a command per family and reads the address from the
+// first line of its output, for addresses only local tooling knows, like
+// the state of a BGP daemon or a keepalived VIP.
+type CommandDetector struct {
+	ipv4    []string
+	ipv6    []string
+	timeout time.Duration
+}
+
+// NewCommandDetector returns a detector running the ipv4 and ipv6 commands,
+// each given as program and arguments. A family without a command fails; a
+// zero timeout means 10 seconds.
+func NewCommandDetector(ipv4, ipv6 []string, timeout time.Duration) (*CommandDetector, error) {
+	if len(ipv4) == 0 && len(ipv6) == 0 {
+		return nil, fmt.Errorf("command detector needs an IPv4 or IPv6 command")
+	}
+	if timeout <= 0 {
+		timeout = defaultCommandTimeout
+	}
+
+	return &CommandDetector{ipv4: ipv4, ipv6: ipv6, timeout: timeout}, nil
+}
+
+func (c *CommandDetector) GetIPv4() (net.IP, error) {
+	ip, err := c.run(c.ipv4, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPv4 from command: %w", err)
+	}
+	return ip, nil
+}
+
+func (c *CommandDetector) GetIPv6() (net.IP, error) {
+	ip, err := c.run(c.ipv6, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPv6 from command: %w", err)
+	}
+	return ip, nil
+}
+
+func (c *CommandDetector) run(command []string, ipv6 bool) (net.IP, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("no %s command configured", familyName(ipv6))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Children that inherited the pipes must not keep us waiting.
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, fmt.Errorf("%s timed out after %s", command[0], c.timeout)
+	case err != nil:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%s exited with status %d%s", command[0], exitErr.ExitCode(), stderrSuffix(stderr.String()))
+		}
+		return nil, fmt.Errorf("failed to run %s: %w", command[0], err)
+	}
+
+	line := firstLine(stdout.String())
+	if line == "" {
+		return nil, fmt.Errorf("%s printed no address", command[0])
+	}
+
+	ip := net.ParseIP(line)
+	if ip == nil {
+		return nil, fmt.Errorf("%s printed an invalid IP address: %s", command[0], line)
+	}
+	if err := checkFamily(ip, ipv6); err != nil {
+		return nil, fmt.Errorf("%s: %w", command[0], err)
+	}
+
+	return ip, nil
+}
+
+func firstLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+func stderrSuffix(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+	if len(stderr) > maxCommandStderr {
+		stderr = stderr[:maxCommandStderr] + "..."
+	}
+	return ": " + stderr
+}
+
+func (c *CommandDetector) Name() string {
+	return "Command"
+}
@@ -0,0 +1,103 @@
+package ip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandDetector(t *testing.T) {
+	tests := []struct {
+		name    string
+		command []string
+		ipv6    bool
+		timeout time.Duration
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "IPv4",
+			command: []string{"sh", "-c", "echo 93.184.216.34"},
+			want:    "93.184.216.34",
+		},
+		{
+			name:    "IPv6 after blank lines",
+			command: []string{"sh", "-c", "printf '\\n  2a01:4f8::1  \\nignored\\n'"},
+			ipv6:    true,
+			want:    "2a01:4f8::1",
+		},
+		{
+			name:    "non-zero exit",
+			command: []string{"sh", "-c", "echo 'bird: connection refused' >&2; exit 3"},
+			wantErr: "failed to get IPv4 from command: sh exited with status 3: bird: connection refused",
+		},
+		{
+			name:    "invalid output",
+			command: []string{"echo", "BACKUP"},
+			wantErr: "failed to get IPv4 from command: echo printed an invalid IP address: BACKUP",
+		},
+		{
+			name:    "no output",
+			command: []string{"true"},
+			wantErr: "failed to get IPv4 from command: true printed no address",
+		},
+		{
+			name:    "wrong family",
+			command: []string{"echo", "93.184.216.34"},
+			ipv6:    true,
+			wantErr: "failed to get IPv6 from command: echo: received IPv4 address instead of IPv6: 93.184.216.34",
+		},
+		{
+			name:    "timeout",
+			command: []string{"sleep", "5"},
+			timeout: 100 * time.Millisecond,
+			wantErr: "failed to get IPv4 from command: sleep timed out after 100ms",
+		},
+		{
+			name:    "missing program",
+			command: []string{"/nonexistent/get-ip"},
+			wantErr: "failed to get IPv4 from command: failed to run /nonexistent/get-ip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipv4, ipv6 []string
+			if tt.ipv6 {
+				ipv6 = tt.command
+			} else {
+				ipv4 = tt.command
+			}
+
+			detector, err := NewCommandDetector(ipv4, ipv6, tt.timeout)
+			require.NoError(t, err)
+
+			get := detector.GetIPv4
+			if tt.ipv6 {
+				get = detector.GetIPv6
+			}
+
+			ip, err := get()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ip.String())
+		})
+	}
+}
+
+func TestCommandDetector_MissingFamily(t *testing.T) {
+	detector, err := NewCommandDetector([]string{"echo", "93.184.216.34"}, nil, 0)
+	require.NoError(t, err)
+
+	_, err = detector.GetIPv6()
+	assert.EqualError(t, err, "failed to get IPv6 from command: no IPv6 command configured")
+
+	_, err = NewCommandDetector(nil, nil, 0)
+	assert.EqualError(t, err, "command detector needs an IPv4 or IPv6 command")
+}
@@ -0,0 +1,115 @@
+package ip
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ConsensusDetector asks several detectors in parallel and only accepts an
+// address that at least quorum of them report. Sources that fail or report
+// a different address are logged as warnings.
+type ConsensusDetector struct {
+	quorum int
+	ipv4   []IPDetector
+	ipv6   []IPDetector
+}
+
+// NewConsensusDetector returns a detector asking ipv4 sources for IPv4 and
+// ipv6 sources for IPv6 addresses. A quorum of zero or less means a
+// majority of the sources of the family.
+func NewConsensusDetector(quorum int, ipv4, ipv6 []IPDetector) *ConsensusDetector {
+	return &ConsensusDetector{quorum: quorum, ipv4: ipv4, ipv6: ipv6}
+}
+
+type vote struct {
+	source string
+	ip     net.IP
+	err    error
+}
+
+func (c *ConsensusDetector) GetIPv4() (net.IP, error) {
+	return c.detect("IPv4", c.ipv4, IPDetector.GetIPv4)
+}
+
+func (c *ConsensusDetector) GetIPv6() (net.IP, error) {
+	return c.detect("IPv6", c.ipv6, IPDetector.GetIPv6)
+}
+
+func (c *ConsensusDetector) detect(family string, sources []IPDetector, get func(IPDetector) (net.IP, error)) (net.IP, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no %s sources configured", family)
+	}
+
+	quorum := c.quorum
+	if quorum <= 0 {
+		quorum = len(sources)/2 + 1
+	}
+
+	votes := make([]vote, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ip, err := get(source)
+			votes[i] = vote{source: source.Name(), ip: ip, err: err}
+		}()
+	}
+	wg.Wait()
+
+	// Group the sources by the address they reported, keeping the order
+	// of the first vote for each address.
+	var addresses []string
+	voters := make(map[string][]string)
+	for _, v := range votes {
+		if v.err != nil {
+			log.Printf("warning: %s source %s failed: %v", family, v.source, v.err)
+			continue
+		}
+
+		address := v.ip.String()
+		if _, seen := voters[address]; !seen {
+			addresses = append(addresses, address)
+		}
+		voters[address] = append(voters[address], v.source)
+	}
+
+	sort.SliceStable(addresses, func(i, j int) bool {
+		return len(voters[addresses[i]]) > len(voters[addresses[j]])
+	})
+
+	if len(addresses) > 1 {
+		log.Printf("warning: %s sources disagree: %s", family, describeVotes(addresses, voters))
+	}
+
+	if len(addresses) == 0 || len(voters[addresses[0]]) < quorum {
+		return nil, fmt.Errorf("no %s address reported by at least %d of %d sources (%s)",
+			family, quorum, len(sources), describeVotes(addresses, voters))
+	}
+
+	if len(addresses) > 1 && len(voters[addresses[1]]) == len(voters[addresses[0]]) {
+		return nil, fmt.Errorf("%s sources are split evenly (%s)", family, describeVotes(addresses, voters))
+	}
+
+	return net.ParseIP(addresses[0]), nil
+}
+
+func describeVotes(addresses []string, voters map[string][]string) string {
+	if len(addresses) == 0 {
+		return "all sources failed"
+	}
+
+	parts := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		parts = append(parts, fmt.Sprintf("%s from %s", address, strings.Join(voters[address], ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (c *ConsensusDetector) Name() string {
+	return "Consensus"
+}
@@ -0,0 +1,156 @@
+package ip
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLog redirects the standard logger to the returned buffer for the
+// rest of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	var logs bytes.Buffer
+	flags, writer := log.Flags(), log.Writer()
+	log.SetFlags(0)
+	log.SetOutput(&logs)
+	t.Cleanup(func() {
+		log.SetFlags(flags)
+		log.SetOutput(writer)
+	})
+	return &logs
+}
+
+// fixedDetector reports fixed results, for composing detectors in tests.
+type fixedDetector struct {
+	name string
+	ipv4 string
+	ipv6 string
+}
+
+func (f fixedDetector) GetIPv4() (net.IP, error) {
+	return f.result(f.ipv4)
+}
+
+func (f fixedDetector) GetIPv6() (net.IP, error) {
+	return f.result(f.ipv6)
+}
+
+func (f fixedDetector) result(address string) (net.IP, error) {
+	if address == "" {
+		return nil, fmt.Errorf("%s unavailable", f.name)
+	}
+	return net.ParseIP(address), nil
+}
+
+func (f fixedDetector) Name() string {
+	return f.name
+}
+
+func TestConsensusDetector(t *testing.T) {
+	tests := []struct {
+		name        string
+		quorum      int
+		sources     []IPDetector
+		expected    string
+		expectError string
+		warnings    []string
+	}{
+		{
+			name:   "all agree",
+			quorum: 2,
+			sources: []IPDetector{
+				fixedDetector{name: "a", ipv4: "203.0.113.7"},
+				fixedDetector{name: "b", ipv4: "203.0.113.7"},
+			},
+			expected: "203.0.113.7",
+		},
+		{
+			name: "majority wins with warning",
+			sources: []IPDetector{
+				fixedDetector{name: "proxy", ipv4: "198.51.100.1"},
+				fixedDetector{name: "a", ipv4: "203.0.113.7"},
+				fixedDetector{name: "b", ipv4: "203.0.113.7"},
+			},
+			expected: "203.0.113.7",
+			warnings: []string{"warning: IPv4 sources disagree: 203.0.113.7 from a, b; 198.51.100.1 from proxy"},
+		},
+		{
+			name:   "failed source does not vote",
+			quorum: 2,
+			sources: []IPDetector{
+				fixedDetector{name: "a", ipv4: "203.0.113.7"},
+				fixedDetector{name: "down"},
+				fixedDetector{name: "b", ipv4: "203.0.113.7"},
+			},
+			expected: "203.0.113.7",
+			warnings: []string{"warning: IPv4 source down failed: down unavailable"},
+		},
+		{
+			name:   "quorum not reached",
+			quorum: 3,
+			sources: []IPDetector{
+				fixedDetector{name: "a", ipv4: "203.0.113.7"},
+				fixedDetector{name: "b", ipv4: "203.0.113.7"},
+				fixedDetector{name: "down"},
+			},
+			expectError: "no IPv4 address reported by at least 3 of 3 sources (203.0.113.7 from a, b)",
+		},
+		{
+			name:   "even split",
+			quorum: 1,
+			sources: []IPDetector{
+				fixedDetector{name: "a", ipv4: "203.0.113.7"},
+				fixedDetector{name: "b", ipv4: "198.51.100.1"},
+			},
+			expectError: "IPv4 sources are split evenly (203.0.113.7 from a; 198.51.100.1 from b)",
+		},
+		{
+			name:        "all failed",
+			sources:     []IPDetector{fixedDetector{name: "down"}},
+			expectError: "no IPv4 address reported by at least 1 of 1 sources (all sources failed)",
+		},
+		{
+			name:        "no sources",
+			expectError: "no IPv4 sources configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLog(t)
+			detector := NewConsensusDetector(tt.quorum, tt.sources, nil)
+
+			ip, err := detector.GetIPv4()
+			if tt.expectError != "" {
+				assert.EqualError(t, err, tt.expectError)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, ip.String())
+			}
+
+			for _, warning := range tt.warnings {
+				assert.Contains(t, logs.String(), warning)
+			}
+		})
+	}
+}
+
+func TestConsensusDetector_SeparateFamilies(t *testing.T) {
+	detector := NewConsensusDetector(0,
+		[]IPDetector{fixedDetector{name: "v4", ipv4: "203.0.113.7"}},
+		[]IPDetector{fixedDetector{name: "v6", ipv6: "2001:db8::7"}, fixedDetector{name: "v6b", ipv6: "2001:db8::7"}},
+	)
+
+	ipv4, err := detector.GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.7", ipv4.String())
+
+	ipv6, err := detector.GetIPv6()
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::7", ipv6.String())
+}
@@ -0,0 +1,230 @@
+package ip
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSAnswerType tells which records a whoami name answers with.
+type DNSAnswerType string
+
+const (
+	// DNSAnswerAddress names answer with an A (or AAAA) record holding the
+	// address of the client, like myip.opendns.com.
+	DNSAnswerAddress DNSAnswerType = "address"
+	// DNSAnswerTXT names answer with a TXT record holding the address of
+	// the client, like o-o.myaddr.l.google.com.
+	DNSAnswerTXT DNSAnswerType = "txt"
+)
+
+const defaultDNSQueryTimeout = 3 * time.Second
+
+// DNSQuery is a "whoami" lookup: Name is resolved at Resolver ("host:port",
+// port 53 by default) and the answer is the address the query came from.
+type DNSQuery struct {
+	Resolver string
+	Name     string
+	Type     DNSAnswerType
+}
+
+var (
+	DefaultIPv4DNSQueries = []DNSQuery{
+		{Resolver: "resolver1.opendns.com", Name: "myip.opendns.com", Type: DNSAnswerAddress},
+		{Resolver: "ns1.google.com", Name: "o-o.myaddr.l.google.com", Type: DNSAnswerTXT},
+	}
+	DefaultIPv6DNSQueries = []DNSQuery{
+		{Resolver: "resolver1.opendns.com", Name: "myip.opendns.com", Type: DNSAnswerAddress},
+		{Resolver: "ns1.google.com", Name: "o-o.myaddr.l.google.com", Type: DNSAnswerTXT},
+	}
+)
+
+// DNSDetector finds the public address by asking special DNS names that
+// answer with the address of the client. Queries are sent over IPv4 for
+// GetIPv4 and over IPv6 for GetIPv6.
+type DNSDetector struct {
+	ipv4    []DNSQuery
+	ipv6    []DNSQuery
+	timeout time.Duration
+}
+
+// NewDNSDetector returns a detector trying the queries of a family in order.
+// A family without queries uses OpenDNS and Google; a zero timeout means 3
+// seconds per query.
+func NewDNSDetector(ipv4, ipv6 []DNSQuery, timeout time.Duration) (*DNSDetector, error) {
+	if len(ipv4) == 0 {
+		ipv4 = DefaultIPv4DNSQueries
+	}
+	if len(ipv6) == 0 {
+		ipv6 = DefaultIPv6DNSQueries
+	}
+	if timeout <= 0 {
+		timeout = defaultDNSQueryTimeout
+	}
+
+	for _, query := range append(append([]DNSQuery{}, ipv4...), ipv6...) {
+		if query.Resolver == "" || query.Name == "" {
+			return nil, fmt.Errorf("DNS query needs a resolver and a name")
+		}
+		switch query.Type {
+		case DNSAnswerAddress, DNSAnswerTXT:
+		default:
+			return nil, fmt.Errorf("DNS query %s: unknown answer type %q", query.Name, query.Type)
+		}
+	}
+
+	return &DNSDetector{ipv4: ipv4, ipv6: ipv6, timeout: timeout}, nil
+}
+
+func (d *DNSDetector) GetIPv4() (net.IP, error) {
+	ip, err := d.lookup(d.ipv4, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPv4 via DNS: %w", err)
+	}
+	return ip, nil
+}
+
+func (d *DNSDetector) GetIPv6() (net.IP, error) {
+	ip, err := d.lookup(d.ipv6, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPv6 via DNS: %w", err)
+	}
+	return ip, nil
+}
+
+func (d *DNSDetector) lookup(queries []DNSQuery, ipv6 bool) (net.IP, error) {
+	var errs []error
+
+	for _, query := range queries {
+		ip, err := d.exchange(query, ipv6)
+		if err == nil {
+			err = checkFamily(ip, ipv6)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s at %s: %w", query.Name, query.Resolver, err))
+			continue
+		}
+
+		return ip, nil
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func (d *DNSDetector) exchange(query DNSQuery, ipv6 bool) (net.IP, error) {
+	name, err := dnsmessage.NewName(fqdn(query.Name))
+	if err != nil {
+		return nil, fmt.Errorf("invalid name: %w", err)
+	}
+
+	questionType := dnsmessage.TypeTXT
+	if query.Type == DNSAnswerAddress {
+		questionType = dnsmessage.TypeA
+		if ipv6 {
+			questionType = dnsmessage.TypeAAAA
+		}
+	}
+
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, fmt.Errorf("failed to create query ID: %w", err)
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	request := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: questionType, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := request.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	network := "udp4"
+	if ipv6 {
+		network = "udp6"
+	}
+
+	conn, err := net.DialTimeout(network, resolverAddress(query.Resolver), d.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(d.timeout))
+	if _, err := conn.Write(packed); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1232)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		var response dnsmessage.Message
+		if err := response.Unpack(buf[:n]); err != nil || response.ID != id || !response.Response {
+			// Not the answer to our query; keep waiting.
+			continue
+		}
+
+		if response.RCode != dnsmessage.RCodeSuccess {
+			return nil, fmt.Errorf("resolver answered %s", response.RCode)
+		}
+
+		return parseWhoamiAnswer(response.Answers, query.Type, ipv6)
+	}
+}
+
+func parseWhoamiAnswer(answers []dnsmessage.Resource, answerType DNSAnswerType, ipv6 bool) (net.IP, error) {
+	for _, answer := range answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			if answerType == DNSAnswerAddress && !ipv6 {
+				return net.IP(body.A[:]), nil
+			}
+		case *dnsmessage.AAAAResource:
+			if answerType == DNSAnswerAddress && ipv6 {
+				return net.IP(body.AAAA[:]), nil
+			}
+		case *dnsmessage.TXTResource:
+			if answerType != DNSAnswerTXT {
+				continue
+			}
+			// Google adds an "edns0-client-subnet ..." string when the
+			// query carries ECS; only bare addresses count.
+			for _, text := range body.TXT {
+				if ip := net.ParseIP(strings.TrimSpace(text)); ip != nil && (ip.To4() == nil) == ipv6 {
+					return ip, nil
+				}
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no address in answer")
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+func resolverAddress(resolver string) string {
+	if _, _, err := net.SplitHostPort(resolver); err == nil {
+		return resolver
+	}
+	return net.JoinHostPort(strings.Trim(resolver, "[]"), "53")
+}
+
+func (d *DNSDetector) Name() string {
+	return "DNS Query"
+}
@@ -0,0 +1,194 @@
+package ip
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// whoamiServer answers every query with the address it holds, as an A/AAAA
+// or TXT record depending on the question.
+type whoamiServer struct {
+	answer net.IP
+	rcode  dnsmessage.RCode
+	txt    []string
+}
+
+func (s *whoamiServer) serve(t *testing.T, network, address string) string {
+	conn, err := net.ListenPacket(network, address)
+	if err != nil {
+		t.Skipf("cannot listen on %s: %v", address, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var request dnsmessage.Message
+			if err := request.Unpack(buf[:n]); err != nil || len(request.Questions) != 1 {
+				continue
+			}
+
+			// A reply with another ID must be ignored.
+			stray := s.response(request)
+			stray.ID++
+			if packed, err := stray.Pack(); err == nil {
+				conn.WriteTo(packed, peer)
+			}
+
+			response := s.response(request)
+			if packed, err := response.Pack(); err == nil {
+				conn.WriteTo(packed, peer)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func (s *whoamiServer) response(request dnsmessage.Message) dnsmessage.Message {
+	question := request.Questions[0]
+	response := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: request.ID, Response: true, RCode: s.rcode},
+		Questions: request.Questions,
+	}
+	if s.rcode != dnsmessage.RCodeSuccess {
+		return response
+	}
+
+	header := dnsmessage.ResourceHeader{Name: question.Name, Type: question.Type, Class: dnsmessage.ClassINET, TTL: 0}
+	switch question.Type {
+	case dnsmessage.TypeA:
+		if ip4 := s.answer.To4(); ip4 != nil {
+			var a [4]byte
+			copy(a[:], ip4)
+			response.Answers = append(response.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AResource{A: a}})
+		}
+	case dnsmessage.TypeAAAA:
+		if s.answer.To4() == nil {
+			var aaaa [16]byte
+			copy(aaaa[:], s.answer.To16())
+			response.Answers = append(response.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AAAAResource{AAAA: aaaa}})
+		}
+	case dnsmessage.TypeTXT:
+		txt := s.txt
+		if txt == nil {
+			txt = []string{s.answer.String()}
+		}
+		response.Answers = append(response.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.TXTResource{TXT: txt}})
+	}
+	return response
+}
+
+func TestDNSDetector_GetIPv4(t *testing.T) {
+	tests := []struct {
+		name       string
+		server     whoamiServer
+		answerType DNSAnswerType
+		want       string
+		wantErr    string
+	}{
+		{
+			name:       "A record",
+			server:     whoamiServer{answer: net.ParseIP("203.0.113.7")},
+			answerType: DNSAnswerAddress,
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "TXT record",
+			server:     whoamiServer{answer: net.ParseIP("198.51.100.4")},
+			answerType: DNSAnswerTXT,
+			want:       "198.51.100.4",
+		},
+		{
+			name:       "TXT record with client subnet",
+			server:     whoamiServer{txt: []string{"edns0-client-subnet 198.51.100.0/24", "198.51.100.4"}},
+			answerType: DNSAnswerTXT,
+			want:       "198.51.100.4",
+		},
+		{
+			name:       "NXDOMAIN",
+			server:     whoamiServer{rcode: dnsmessage.RCodeNameError},
+			answerType: DNSAnswerAddress,
+			wantErr:    "resolver answered RCodeNameError",
+		},
+		{
+			name:       "TXT without an address",
+			server:     whoamiServer{txt: []string{"hello"}},
+			answerType: DNSAnswerTXT,
+			wantErr:    "no address in answer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := tt.server.serve(t, "udp4", "127.0.0.1:0")
+
+			detector, err := NewDNSDetector([]DNSQuery{{Resolver: address, Name: "whoami.example", Type: tt.answerType}}, nil, 0)
+			require.NoError(t, err)
+
+			ip, err := detector.GetIPv4()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ip.String())
+		})
+	}
+}
+
+func TestDNSDetector_GetIPv6(t *testing.T) {
+	server := whoamiServer{answer: net.ParseIP("2001:db8::7")}
+	address := server.serve(t, "udp6", "[::1]:0")
+
+	detector, err := NewDNSDetector(nil, []DNSQuery{
+		{Resolver: address, Name: "whoami.example", Type: DNSAnswerAddress},
+		{Resolver: address, Name: "whoami.example", Type: DNSAnswerTXT},
+	}, 0)
+	require.NoError(t, err)
+
+	ip, err := detector.GetIPv6()
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::7", ip.String())
+}
+
+func TestDNSDetector_FallsBack(t *testing.T) {
+	failing := whoamiServer{rcode: dnsmessage.RCodeServerFailure}
+	working := whoamiServer{answer: net.ParseIP("203.0.113.9")}
+
+	detector, err := NewDNSDetector([]DNSQuery{
+		{Resolver: failing.serve(t, "udp4", "127.0.0.1:0"), Name: "whoami.example", Type: DNSAnswerAddress},
+		{Resolver: working.serve(t, "udp4", "127.0.0.1:0"), Name: "whoami.example", Type: DNSAnswerTXT},
+	}, nil, 0)
+	require.NoError(t, err)
+
+	ip, err := detector.GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.9", ip.String())
+}
+
+func TestNewDNSDetector_Invalid(t *testing.T) {
+	_, err := NewDNSDetector([]DNSQuery{{Resolver: "127.0.0.1", Name: "whoami.example", Type: "srv"}}, nil, 0)
+	assert.ErrorContains(t, err, "unknown answer type")
+
+	_, err = NewDNSDetector(nil, []DNSQuery{{Name: "whoami.example", Type: DNSAnswerTXT}}, 0)
+	assert.ErrorContains(t, err, "needs a resolver")
+}
+
+func TestResolverAddress(t *testing.T) {
+	assert.Equal(t, "resolver1.opendns.com:53", resolverAddress("resolver1.opendns.com"))
+	assert.Equal(t, "127.0.0.1:5353", resolverAddress("127.0.0.1:5353"))
+	assert.Equal(t, "[2001:db8::1]:53", resolverAddress("2001:db8::1"))
+	assert.Equal(t, "[2001:db8::1]:53", resolverAddress("[2001:db8::1]"))
+}
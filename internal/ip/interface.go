@@ -3,56 +3,202 @@ package ip
 import (
 	"fmt"
 	"net"
+	"path"
+	"slices"
+	"sort"
+	"strings"
 )
 
-type InterfaceDetector struct{}
+// InterfaceAddr is an address assigned to a network interface.
+type InterfaceAddr struct {
+	Interface string
+	IP        net.IP
+	// Temporary marks IPv6 privacy addresses (RFC 8981).
+	Temporary bool
+	// Deprecated marks addresses past their preferred lifetime.
+	Deprecated bool
+}
+
+// InterfaceLister returns the addresses of the interfaces that are up, in
+// interface order.
+type InterfaceLister func() ([]InterfaceAddr, error)
+
+// InterfaceDetector reads the public addresses assigned to the local
+// interfaces. Interfaces can be selected and excluded by name or glob; IPv6
+// prefers stable addresses over temporary and deprecated ones.
+type InterfaceDetector struct {
+	include []string
+	exclude []string
+
+	// Classes lists the address classes that may be returned, most wanted
+	// first; nil means public addresses only. Set it to ClassPrivate to
+	// publish LAN addresses for internal names.
+	Classes []AddressClass
+
+	// Lister lists the interface addresses; nil means the system interfaces.
+	Lister InterfaceLister
+}
 
 func NewInterfaceDetector() *InterfaceDetector {
 	return &InterfaceDetector{}
 }
 
+// NewInterfaceDetectorWithFilter returns a detector that only reads the
+// interfaces matching one of the include patterns, or all interfaces when
+// include is empty, and none matching an exclude pattern. Patterns are
+// names or globs like "eth*". Interfaces are preferred in the order of the
+// include pattern they match.
+func NewInterfaceDetectorWithFilter(include, exclude []string) (*InterfaceDetector, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid interface pattern %q: %w", pattern, err)
+		}
+	}
+
+	return &InterfaceDetector{include: include, exclude: exclude}, nil
+}
+
 func (i *InterfaceDetector) GetIPv4() (net.IP, error) {
-	interfaces, err := net.Interfaces()
+	addrs, err := i.candidates(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no %s IPv4 address found", i.classNames())
+	}
+	return addrs[0].IP, nil
+}
+
+func (i *InterfaceDetector) GetIPv6() (net.IP, error) {
+	addrs, err := i.candidates(true)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no %s IPv6 address found", i.classNames())
+	}
+	return addrs[0].IP, nil
+}
+
+// candidates returns the addresses of the family and wanted classes on the
+// selected interfaces, best first: by include pattern, then by class, then
+// stable before temporary before deprecated, then in interface order.
+func (i *InterfaceDetector) candidates(ipv6 bool) ([]InterfaceAddr, error) {
+	lister := i.Lister
+	if lister == nil {
+		lister = systemInterfaceAddrs
+	}
+
+	addrs, err := lister()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get network interfaces: %w", err)
 	}
 
-	for _, iface := range interfaces {
-		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+	type candidate struct {
+		addr    InterfaceAddr
+		pattern int
+		class   int
+		rank    int
+	}
+
+	var candidates []candidate
+	for _, addr := range addrs {
+		if (addr.IP.To4() == nil) != ipv6 {
 			continue
 		}
 
-		addrs, err := iface.Addrs()
-		if err != nil {
+		class := slices.Index(i.classes(), ClassifyAddress(addr.IP))
+		if class < 0 {
 			continue
 		}
 
-		for _, addr := range addrs {
-			ipNet, ok := addr.(*net.IPNet)
-			if !ok {
-				continue
-			}
+		pattern, ok := i.match(addr.Interface)
+		if !ok {
+			continue
+		}
 
-			ip := ipNet.IP
-			if ip.IsLoopback() || ip.IsPrivate() {
-				continue
-			}
+		rank := 0
+		switch {
+		case addr.Deprecated:
+			rank = 2
+		case addr.Temporary:
+			rank = 1
+		}
 
-			if ip.To4() != nil {
-				return ip, nil
-			}
+		candidates = append(candidates, candidate{addr: addr, pattern: pattern, class: class, rank: rank})
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if candidates[a].pattern != candidates[b].pattern {
+			return candidates[a].pattern < candidates[b].pattern
+		}
+		if candidates[a].class != candidates[b].class {
+			return candidates[a].class < candidates[b].class
 		}
+		return candidates[a].rank < candidates[b].rank
+	})
+
+	result := make([]InterfaceAddr, len(candidates))
+	for n, c := range candidates {
+		result[n] = c.addr
 	}
+	return result, nil
+}
 
-	return nil, fmt.Errorf("no public IPv4 address found")
+// Relevant reports whether addr on the interface called iface is one the
+// detector could return, so that a change of it may change the result.
+func (i *InterfaceDetector) Relevant(iface string, addr net.IP) bool {
+	if _, ok := i.match(iface); !ok {
+		return false
+	}
+	return slices.Contains(i.classes(), ClassifyAddress(addr))
 }
 
-func (i *InterfaceDetector) GetIPv6() (net.IP, error) {
+// match reports whether the interface is selected and the index of the
+// include pattern it matches.
+func (i *InterfaceDetector) match(name string) (int, bool) {
+	for _, pattern := range i.exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			return 0, false
+		}
+	}
+
+	if len(i.include) == 0 {
+		return 0, true
+	}
+
+	for n, pattern := range i.include {
+		if ok, _ := path.Match(pattern, name); ok {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+func (i *InterfaceDetector) classes() []AddressClass {
+	if len(i.Classes) == 0 {
+		return []AddressClass{ClassPublic}
+	}
+	return i.Classes
+}
+
+func (i *InterfaceDetector) classNames() string {
+	names := make([]string, len(i.classes()))
+	for n, class := range i.classes() {
+		names[n] = string(class)
+	}
+	return strings.Join(names, " or ")
+}
+
+func systemInterfaceAddrs() ([]InterfaceAddr, error) {
 	interfaces, err := net.Interfaces()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get network interfaces: %w", err)
+		return nil, err
 	}
 
+	flags := ipv6AddressFlags()
+
+	var result []InterfaceAddr
 	for _, iface := range interfaces {
 		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
 			continue
@@ -69,20 +215,27 @@ func (i *InterfaceDetector) GetIPv6() (net.IP, error) {
 				continue
 			}
 
-			ip := ipNet.IP
-			if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() {
-				continue
-			}
-
-			if ip.To4() == nil {
-				return ip, nil
+			entry := InterfaceAddr{Interface: iface.Name, IP: ipNet.IP}
+			if ipNet.IP.To4() == nil {
+				f := flags[ipNet.IP.String()]
+				entry.Temporary = f.temporary
+				entry.Deprecated = f.deprecated
 			}
+			result = append(result, entry)
 		}
 	}
 
-	return nil, fmt.Errorf("no public IPv6 address found")
+	return result, nil
+}
+
+type addressFlags struct {
+	temporary  bool
+	deprecated bool
 }
 
 func (i *InterfaceDetector) Name() string {
-	return "Network Interface"
+	if len(i.Classes) == 0 || slices.Equal(i.Classes, []AddressClass{ClassPublic}) {
+		return "Network Interface"
+	}
+	return fmt.Sprintf("Network Interface (%s)", i.classNames())
 }
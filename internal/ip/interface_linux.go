@@ -0,0 +1,59 @@
+package ip
+
+import (
+	"bufio"
+	"encoding/hex"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Address flags from linux/if_addr.h.
+const (
+	ifaFlagTemporary  = 0x01
+	ifaFlagDeprecated = 0x20
+)
+
+// ipv6AddressFlags reads the flags of the IPv6 addresses, keyed by address,
+// from /proc/net/if_inet6. net.Interfaces does not expose them.
+func ipv6AddressFlags() map[string]addressFlags {
+	file, err := os.Open("/proc/net/if_inet6")
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	return parseIfInet6(bufio.NewScanner(file))
+}
+
+// parseIfInet6 parses lines like
+// "20010db8000000000000000000000001 02 40 00 80 eth0": address, interface
+// index, prefix length, scope, flags and interface name.
+func parseIfInet6(scanner *bufio.Scanner) map[string]addressFlags {
+	result := make(map[string]addressFlags)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+
+		raw, err := hex.DecodeString(fields[0])
+		if err != nil || len(raw) != net.IPv6len {
+			continue
+		}
+
+		flags, err := strconv.ParseUint(fields[4], 16, 32)
+		if err != nil {
+			continue
+		}
+
+		result[net.IP(raw).String()] = addressFlags{
+			temporary:  flags&ifaFlagTemporary != 0,
+			deprecated: flags&ifaFlagDeprecated != 0,
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,26 @@
+package ip
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIfInet6(t *testing.T) {
+	input := strings.Join([]string{
+		"20010db8000000000000000000000001 02 40 00 80     eth0",
+		"20010db80000000011112222333344 02 40 00 01     eth0", // truncated
+		"20010db8000000001111222233334444 02 40 00 01     eth0",
+		"20010db8000000000000000000000dea 02 40 00 20     eth0",
+		"fe800000000000000000000000000001 02 40 20 80     eth0",
+	}, "\n")
+
+	flags := parseIfInet6(bufio.NewScanner(strings.NewReader(input)))
+
+	assert.Equal(t, addressFlags{}, flags["2001:db8::1"])
+	assert.Equal(t, addressFlags{temporary: true}, flags["2001:db8::1111:2222:3333:4444"])
+	assert.Equal(t, addressFlags{deprecated: true}, flags["2001:db8::dea"])
+	assert.Len(t, flags, 4)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package ip
+
+// ipv6AddressFlags is only implemented on Linux; elsewhere every address
+// counts as stable.
+func ipv6AddressFlags() map[string]addressFlags {
+	return nil
+}
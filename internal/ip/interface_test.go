@@ -0,0 +1,205 @@
+package ip
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func staticLister(addrs ...InterfaceAddr) InterfaceLister {
+	return func() ([]InterfaceAddr, error) {
+		return addrs, nil
+	}
+}
+
+func addr(iface, ip string) InterfaceAddr {
+	return InterfaceAddr{Interface: iface, IP: net.ParseIP(ip)}
+}
+
+func TestInterfaceDetector_GetIPv4(t *testing.T) {
+	addrs := []InterfaceAddr{
+		addr("docker0", "93.184.216.99"),
+		addr("eth0", "192.168.1.10"),
+		addr("eth0", "169.254.0.1"),
+		addr("eth1", "93.184.215.2"),
+		addr("wg0", "93.184.216.5"),
+		addr("wg0", "2a01:4f8::5"),
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "first public address",
+			want: "93.184.216.99",
+		},
+		{
+			name:    "excluded interfaces",
+			exclude: []string{"docker*", "veth*"},
+			want:    "93.184.215.2",
+		},
+		{
+			name:    "include order wins over interface order",
+			include: []string{"wg*", "eth*"},
+			want:    "93.184.216.5",
+		},
+		{
+			name:    "included but excluded",
+			include: []string{"docker0"},
+			exclude: []string{"docker*"},
+			wantErr: "no public IPv4 address found",
+		},
+		{
+			name:    "only private addresses",
+			include: []string{"eth0"},
+			wantErr: "no public IPv4 address found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector, err := NewInterfaceDetectorWithFilter(tt.include, tt.exclude)
+			require.NoError(t, err)
+			detector.Lister = staticLister(addrs...)
+
+			ip, err := detector.GetIPv4()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ip.String())
+		})
+	}
+}
+
+func TestInterfaceDetector_GetIPv6(t *testing.T) {
+	temporary := addr("eth0", "2a01:4f8::aaaa")
+	temporary.Temporary = true
+	deprecated := addr("eth0", "2a01:4f8::dead")
+	deprecated.Deprecated = true
+	deprecatedTemporary := addr("eth0", "2a01:4f8::bbbb")
+	deprecatedTemporary.Temporary = true
+	deprecatedTemporary.Deprecated = true
+
+	tests := []struct {
+		name    string
+		include []string
+		addrs   []InterfaceAddr
+		want    string
+	}{
+		{
+			name:  "stable before temporary",
+			addrs: []InterfaceAddr{temporary, addr("eth0", "2a01:4f8::1")},
+			want:  "2a01:4f8::1",
+		},
+		{
+			name:  "temporary before deprecated",
+			addrs: []InterfaceAddr{deprecated, deprecatedTemporary, temporary},
+			want:  "2a01:4f8::aaaa",
+		},
+		{
+			name:  "deprecated as last resort",
+			addrs: []InterfaceAddr{addr("eth0", "fd00::1"), addr("eth0", "fe80::1"), deprecated},
+			want:  "2a01:4f8::dead",
+		},
+		{
+			name:    "include order before stability",
+			include: []string{"eth0", "eth1"},
+			addrs:   []InterfaceAddr{addr("eth1", "2a01:4f8:1::1"), temporary},
+			want:    "2a01:4f8::aaaa",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector, err := NewInterfaceDetectorWithFilter(tt.include, nil)
+			require.NoError(t, err)
+			detector.Lister = staticLister(tt.addrs...)
+
+			ip, err := detector.GetIPv6()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ip.String())
+		})
+	}
+}
+
+func TestInterfaceDetector_ListerError(t *testing.T) {
+	detector := NewInterfaceDetector()
+	detector.Lister = func() ([]InterfaceAddr, error) {
+		return nil, errors.New("boom")
+	}
+
+	_, err := detector.GetIPv4()
+	assert.EqualError(t, err, "failed to get network interfaces: boom")
+}
+
+func TestNewInterfaceDetectorWithFilter_InvalidPattern(t *testing.T) {
+	_, err := NewInterfaceDetectorWithFilter([]string{"eth["}, nil)
+	assert.ErrorContains(t, err, `invalid interface pattern "eth["`)
+}
+
+func TestInterfaceDetector_AddressClasses(t *testing.T) {
+	addrs := staticLister(
+		addr("eth0", "100.64.12.34"),
+		addr("eth0", "192.0.2.10"),
+		addr("eth0", "198.18.0.1"),
+		addr("eth0", "192.168.1.10"),
+		addr("eth0", "93.184.216.34"),
+		addr("eth0", "2001:db8::1"),
+		addr("eth0", "fd12:3456::10"),
+		addr("eth0", "2a01:4f8::1"),
+	)
+
+	detector := NewInterfaceDetector()
+	detector.Lister = addrs
+
+	ipv4, err := detector.GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "93.184.216.34", ipv4.String())
+
+	ipv6, err := detector.GetIPv6()
+	require.NoError(t, err)
+	assert.Equal(t, "2a01:4f8::1", ipv6.String())
+
+	lan := NewInterfaceDetector()
+	lan.Classes = []AddressClass{ClassPrivate}
+	lan.Lister = addrs
+
+	ipv4, err = lan.GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.10", ipv4.String())
+
+	ipv6, err = lan.GetIPv6()
+	require.NoError(t, err)
+	assert.Equal(t, "fd12:3456::10", ipv6.String())
+	assert.Equal(t, "Network Interface (private)", lan.Name())
+
+	cgnat := NewInterfaceDetector()
+	cgnat.Lister = staticLister(addr("eth0", "100.64.12.34"))
+
+	_, err = cgnat.GetIPv4()
+	assert.EqualError(t, err, "no public IPv4 address found")
+
+	cgnat.Classes = []AddressClass{ClassPublic, ClassShared}
+	ipv4, err = cgnat.GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "100.64.12.34", ipv4.String())
+}
+
+func TestInterfaceDetector_Relevant(t *testing.T) {
+	detector, err := NewInterfaceDetectorWithFilter(nil, []string{"docker*"})
+	require.NoError(t, err)
+
+	assert.True(t, detector.Relevant("eth0", net.ParseIP("93.184.216.34")))
+	assert.False(t, detector.Relevant("eth0", net.ParseIP("192.168.1.10")))
+	assert.False(t, detector.Relevant("docker0", net.ParseIP("93.184.216.34")))
+}
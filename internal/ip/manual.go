@@ -3,15 +3,19 @@ package ip
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"strings"
 )
 
-type ManualDetector struct{}
+// ManualDetector prompts on stdin; prompts and validation messages go to out.
+type ManualDetector struct {
+	out io.Writer
+}
 
-func NewManualDetector() *ManualDetector {
-	return &ManualDetector{}
+func NewManualDetector(out io.Writer) *ManualDetector {
+	return &ManualDetector{out: out}
 }
 
 func (m *ManualDetector) GetIPv4() (net.IP, error) {
@@ -26,7 +30,7 @@ func (m *ManualDetector) promptForIP(ipType string) (net.IP, error) {
 	scanner := bufio.NewScanner(os.Stdin)
 
 	for {
-		fmt.Printf("Enter %s address: ", ipType)
+		fmt.Fprintf(m.out, "Enter %s address: ", ipType)
 		if !scanner.Scan() {
 			return nil, fmt.Errorf("failed to read input")
 		}
@@ -38,17 +42,17 @@ func (m *ManualDetector) promptForIP(ipType string) (net.IP, error) {
 
 		ip := net.ParseIP(input)
 		if ip == nil {
-			fmt.Printf("Invalid IP address format: %s\n", input)
+			fmt.Fprintf(m.out, "Invalid IP address format: %s\n", input)
 			continue
 		}
 
 		if ipType == "IPv4" && ip.To4() == nil {
-			fmt.Printf("Please enter a valid IPv4 address, got: %s\n", input)
+			fmt.Fprintf(m.out, "Please enter a valid IPv4 address, got: %s\n", input)
 			continue
 		}
 
 		if ipType == "IPv6" && ip.To4() != nil {
-			fmt.Printf("Please enter a valid IPv6 address, got: %s\n", input)
+			fmt.Fprintf(m.out, "Please enter a valid IPv6 address, got: %s\n", input)
 			continue
 		}
 
@@ -0,0 +1,290 @@
+package ip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cloud is a cloud provider whose instance metadata service knows the
+// public addresses of the instance.
+type Cloud string
+
+const (
+	CloudAWS          Cloud = "aws"
+	CloudGCP          Cloud = "gcp"
+	CloudHetzner      Cloud = "hetzner"
+	CloudDigitalOcean Cloud = "digitalocean"
+	CloudOracle       Cloud = "oracle"
+)
+
+// Clouds lists the supported clouds in the order they win auto-detection.
+var Clouds = []Cloud{CloudAWS, CloudGCP, CloudHetzner, CloudDigitalOcean, CloudOracle}
+
+const (
+	defaultMetadataEndpoint = "http://169.254.169.254"
+	defaultMetadataTimeout  = 2 * time.Second
+)
+
+// cloudMetadata describes the metadata service of a cloud. Paths are
+// relative to the metadata endpoint; an empty path means the cloud does
+// not publish that family.
+type cloudMetadata struct {
+	probe string
+	ipv4  string
+	ipv6  string
+	// field is the JSON field path of the addresses, for services that
+	// answer with JSON instead of plain text.
+	field   string
+	headers map[string]string
+	// token fetches the session token of services that need one.
+	token func(d *MetadataDetector, ctx context.Context) (map[string]string, error)
+}
+
+var cloudMetadataServices = map[Cloud]cloudMetadata{
+	CloudAWS: {
+		probe: "/latest/meta-data/instance-id",
+		ipv4:  "/latest/meta-data/public-ipv4",
+		ipv6:  "/latest/meta-data/ipv6",
+		token: (*MetadataDetector).awsToken,
+	},
+	CloudGCP: {
+		probe:   "/computeMetadata/v1/instance/id",
+		ipv4:    "/computeMetadata/v1/instance/network-interfaces/0/access-configs/0/external-ip",
+		ipv6:    "/computeMetadata/v1/instance/network-interfaces/0/ipv6s",
+		headers: map[string]string{"Metadata-Flavor": "Google"},
+	},
+	CloudHetzner: {
+		probe: "/hetzner/v1/metadata/instance-id",
+		ipv4:  "/hetzner/v1/metadata/public-ipv4",
+	},
+	CloudDigitalOcean: {
+		probe: "/metadata/v1/id",
+		ipv4:  "/metadata/v1/interfaces/public/0/ipv4/address",
+		ipv6:  "/metadata/v1/interfaces/public/0/ipv6/address",
+	},
+	CloudOracle: {
+		probe:   "/opc/v2/instance/id",
+		ipv6:    "/opc/v2/vnics/",
+		field:   "0.ipv6Addresses.0",
+		headers: map[string]string{"Authorization": "Bearer Oracle"},
+	},
+}
+
+// MetadataDetector reads the public addresses of a cloud instance from the
+// metadata service of its cloud. These addresses are usually NATed and
+// never appear on an interface.
+type MetadataDetector struct {
+	client   *http.Client
+	endpoint string
+	timeout  time.Duration
+
+	mu    sync.Mutex
+	cloud Cloud
+}
+
+// NewMetadataDetector returns a detector for cloud, or for the cloud found
+// by probing the metadata services when cloud is empty. A zero timeout
+// means 2 seconds per request.
+func NewMetadataDetector(cloud Cloud, timeout time.Duration) (*MetadataDetector, error) {
+	if _, ok := cloudMetadataServices[cloud]; cloud != "" && !ok {
+		return nil, fmt.Errorf("unknown cloud: %s", cloud)
+	}
+	if timeout <= 0 {
+		timeout = defaultMetadataTimeout
+	}
+
+	return &MetadataDetector{
+		// Metadata services are link-local; never go through a proxy.
+		client:   &http.Client{Transport: &http.Transport{Proxy: nil}},
+		endpoint: defaultMetadataEndpoint,
+		timeout:  timeout,
+		cloud:    cloud,
+	}, nil
+}
+
+func (d *MetadataDetector) GetIPv4() (net.IP, error) {
+	ip, err := d.lookup(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPv4 from cloud metadata: %w", err)
+	}
+	return ip, nil
+}
+
+func (d *MetadataDetector) GetIPv6() (net.IP, error) {
+	ip, err := d.lookup(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPv6 from cloud metadata: %w", err)
+	}
+	return ip, nil
+}
+
+func (d *MetadataDetector) lookup(ipv6 bool) (net.IP, error) {
+	cloud, err := d.detectCloud()
+	if err != nil {
+		return nil, err
+	}
+
+	service := cloudMetadataServices[cloud]
+	path := service.ipv4
+	if ipv6 {
+		path = service.ipv6
+	}
+	if path == "" {
+		return nil, fmt.Errorf("%s metadata has no public %s address", cloud, familyName(ipv6))
+	}
+
+	body, err := d.get(cloud, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cloud, err)
+	}
+
+	value := string(body)
+	if service.field != "" {
+		var document any
+		if err := json.Unmarshal(body, &document); err != nil {
+			return nil, fmt.Errorf("%s: invalid JSON response: %w", cloud, err)
+		}
+		if value, err = lookupField(document, service.field); err != nil {
+			return nil, fmt.Errorf("%s: %w", cloud, err)
+		}
+	}
+
+	// Services listing several addresses put one per line.
+	value, _, _ = strings.Cut(strings.TrimSpace(value), "\n")
+	ip := net.ParseIP(strings.TrimSpace(value))
+	if ip == nil {
+		// An instance without a public address gets an empty answer.
+		return nil, fmt.Errorf("%s: no public %s address in metadata", cloud, familyName(ipv6))
+	}
+	if err := checkFamily(ip, ipv6); err != nil {
+		return nil, fmt.Errorf("%s: %w", cloud, err)
+	}
+
+	return ip, nil
+}
+
+// detectCloud returns the configured cloud, or probes all metadata services
+// in parallel and remembers the first cloud in Clouds order that answered.
+func (d *MetadataDetector) detectCloud() (Cloud, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cloud != "" {
+		return d.cloud, nil
+	}
+
+	found := make([]bool, len(Clouds))
+	var wg sync.WaitGroup
+	for i, cloud := range Clouds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := d.get(cloud, cloudMetadataServices[cloud].probe)
+			found[i] = err == nil
+		}()
+	}
+	wg.Wait()
+
+	for i, cloud := range Clouds {
+		if found[i] {
+			d.cloud = cloud
+			return cloud, nil
+		}
+	}
+
+	return "", fmt.Errorf("no cloud metadata service found")
+}
+
+func (d *MetadataDetector) get(cloud Cloud, path string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	service := cloudMetadataServices[cloud]
+
+	headers := service.headers
+	if service.token != nil {
+		var err error
+		if headers, err = service.token(d, ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata service returned status %d for %s", resp.StatusCode, path)
+	}
+	// GCP answers every request with this header; other services on the
+	// same address must not be mistaken for it.
+	if flavor, ok := service.headers["Metadata-Flavor"]; ok && resp.Header.Get("Metadata-Flavor") != flavor {
+		return nil, fmt.Errorf("metadata service is not %s", cloud)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxEchoResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return body, nil
+}
+
+// awsToken fetches an IMDSv2 session token.
+func (d *MetadataDetector) awsToken(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, d.endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata service returned status %d for token", resp.StatusCode)
+	}
+
+	token, err := io.ReadAll(io.LimitReader(resp.Body, maxEchoResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token: %w", err)
+	}
+
+	return map[string]string{"X-aws-ec2-metadata-token": strings.TrimSpace(string(token))}, nil
+}
+
+func familyName(ipv6 bool) string {
+	if ipv6 {
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+func (d *MetadataDetector) Name() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cloud == "" {
+		return "Cloud Metadata"
+	}
+	return fmt.Sprintf("Cloud Metadata (%s)", d.cloud)
+}
@@ -0,0 +1,198 @@
+package ip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMetadataService serves the given paths when the request carries the
+// wanted headers, like the metadata service of a cloud.
+func fakeMetadataService(t *testing.T, headers map[string]string, responseHeaders map[string]string, paths map[string]string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for key, value := range headers {
+			if r.Header.Get(key) != value {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		}
+
+		body, ok := paths[r.Method+" "+r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		for key, value := range responseHeaders {
+			w.Header().Set(key, value)
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func newTestMetadataDetector(t *testing.T, cloud Cloud, endpoint string) *MetadataDetector {
+	detector, err := NewMetadataDetector(cloud, 0)
+	require.NoError(t, err)
+	detector.endpoint = endpoint
+	return detector
+}
+
+func TestMetadataDetector_AWS(t *testing.T) {
+	var tokenRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/latest/api/token" {
+			assert.Equal(t, "60", r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+			tokenRequests++
+			w.Write([]byte("secret-token"))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "secret-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/latest/meta-data/instance-id":
+			w.Write([]byte("i-0123456789abcdef0"))
+		case "/latest/meta-data/public-ipv4":
+			w.Write([]byte("3.120.1.2"))
+		case "/latest/meta-data/ipv6":
+			w.Write([]byte("2a05:d014::1"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	detector := newTestMetadataDetector(t, "", server.URL)
+
+	ipv4, err := detector.GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "3.120.1.2", ipv4.String())
+	assert.Equal(t, "Cloud Metadata (aws)", detector.Name())
+
+	ipv6, err := detector.GetIPv6()
+	require.NoError(t, err)
+	assert.Equal(t, "2a05:d014::1", ipv6.String())
+	assert.Positive(t, tokenRequests)
+}
+
+func TestMetadataDetector_Clouds(t *testing.T) {
+	tests := []struct {
+		name            string
+		cloud           Cloud
+		headers         map[string]string
+		responseHeaders map[string]string
+		paths           map[string]string
+		wantIPv4        string
+		wantIPv6        string
+		wantIPv4Err     string
+		wantIPv6Err     string
+	}{
+		{
+			name:            "GCP",
+			cloud:           CloudGCP,
+			headers:         map[string]string{"Metadata-Flavor": "Google"},
+			responseHeaders: map[string]string{"Metadata-Flavor": "Google"},
+			paths: map[string]string{
+				"GET /computeMetadata/v1/instance/id":                                                "1234",
+				"GET /computeMetadata/v1/instance/network-interfaces/0/access-configs/0/external-ip": "34.1.2.3",
+				"GET /computeMetadata/v1/instance/network-interfaces/0/ipv6s":                        "2600:1900::1\n2600:1900::2\n",
+			},
+			wantIPv4: "34.1.2.3",
+			wantIPv6: "2600:1900::1",
+		},
+		{
+			name:  "Hetzner",
+			cloud: CloudHetzner,
+			paths: map[string]string{
+				"GET /hetzner/v1/metadata/instance-id": "42",
+				"GET /hetzner/v1/metadata/public-ipv4": "49.12.1.2",
+			},
+			wantIPv4:    "49.12.1.2",
+			wantIPv6Err: "hetzner metadata has no public IPv6 address",
+		},
+		{
+			name:  "DigitalOcean",
+			cloud: CloudDigitalOcean,
+			paths: map[string]string{
+				"GET /metadata/v1/id":                               "42",
+				"GET /metadata/v1/interfaces/public/0/ipv4/address": "164.90.1.2",
+				"GET /metadata/v1/interfaces/public/0/ipv6/address": "2a03:b0c0:3::1",
+			},
+			wantIPv4: "164.90.1.2",
+			wantIPv6: "2a03:b0c0:3::1",
+		},
+		{
+			name:    "Oracle",
+			cloud:   CloudOracle,
+			headers: map[string]string{"Authorization": "Bearer Oracle"},
+			paths: map[string]string{
+				"GET /opc/v2/instance/id": "ocid1.instance.oc1..x",
+				"GET /opc/v2/vnics/":      `[{"vnicId": "ocid1.vnic.oc1..x", "privateIp": "10.0.0.2", "ipv6Addresses": ["2603:c020::1"]}]`,
+			},
+			wantIPv4Err: "oracle metadata has no public IPv4 address",
+			wantIPv6:    "2603:c020::1",
+		},
+		{
+			name:  "instance without public address",
+			cloud: CloudDigitalOcean,
+			paths: map[string]string{
+				"GET /metadata/v1/id":                               "42",
+				"GET /metadata/v1/interfaces/public/0/ipv4/address": "",
+			},
+			wantIPv4Err: "digitalocean: no public IPv4 address in metadata",
+			wantIPv6Err: "metadata service returned status 404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := fakeMetadataService(t, tt.headers, tt.responseHeaders, tt.paths)
+
+			// Auto-detection must find the same cloud as configuring it.
+			for _, cloud := range []Cloud{"", tt.cloud} {
+				detector := newTestMetadataDetector(t, cloud, endpoint)
+
+				ipv4, err := detector.GetIPv4()
+				if tt.wantIPv4Err != "" {
+					assert.ErrorContains(t, err, tt.wantIPv4Err)
+				} else if assert.NoError(t, err) {
+					assert.Equal(t, tt.wantIPv4, ipv4.String())
+				}
+
+				ipv6, err := detector.GetIPv6()
+				if tt.wantIPv6Err != "" {
+					assert.ErrorContains(t, err, tt.wantIPv6Err)
+				} else if assert.NoError(t, err) {
+					assert.Equal(t, tt.wantIPv6, ipv6.String())
+				}
+
+				assert.Equal(t, "Cloud Metadata ("+string(tt.cloud)+")", detector.Name())
+			}
+		})
+	}
+}
+
+func TestMetadataDetector_NoCloud(t *testing.T) {
+	endpoint := fakeMetadataService(t, nil, nil, map[string]string{
+		// Looks like GCP, but without the Metadata-Flavor header.
+		"GET /computeMetadata/v1/instance/id": "1234",
+	})
+
+	detector := newTestMetadataDetector(t, "", endpoint)
+
+	_, err := detector.GetIPv4()
+	assert.EqualError(t, err, "failed to get IPv4 from cloud metadata: no cloud metadata service found")
+	assert.Equal(t, "Cloud Metadata", detector.Name())
+}
+
+func TestNewMetadataDetector_UnknownCloud(t *testing.T) {
+	_, err := NewMetadataDetector("azure", 0)
+	assert.EqualError(t, err, "unknown cloud: azure")
+}
@@ -0,0 +1,64 @@
+package ip
+
+import (
+	"fmt"
+	"net"
+)
+
+// ComposeIPv6 returns the first prefixLength bits of prefix followed by the
+// remaining bits of suffix. It puts a fixed interface ID behind the current
+// prefix of a network, like "2001:db8:1:2::/64" + "::10".
+func ComposeIPv6(prefix net.IP, prefixLength int, suffix net.IP) (net.IP, error) {
+	if prefixLength < 0 || prefixLength > 128 {
+		return nil, fmt.Errorf("invalid IPv6 prefix length: %d", prefixLength)
+	}
+
+	prefix16, suffix16 := prefix.To16(), suffix.To16()
+	if prefix16 == nil || prefix.To4() != nil {
+		return nil, fmt.Errorf("not an IPv6 prefix: %s", prefix)
+	}
+	if suffix16 == nil || suffix.To4() != nil {
+		return nil, fmt.Errorf("not an IPv6 suffix: %s", suffix)
+	}
+
+	mask := net.CIDRMask(prefixLength, 128)
+	result := make(net.IP, net.IPv6len)
+	for i := range result {
+		result[i] = prefix16[i]&mask[i] | suffix16[i]&^mask[i]
+	}
+	return result, nil
+}
+
+// ParseInterfaceID parses the host part of an IPv6 address: either an
+// address like "::10" whose low bits are used, or a MAC address like
+// "52:54:00:12:34:56", which becomes its modified EUI-64 interface ID as
+// used by SLAAC.
+func ParseInterfaceID(value string) (net.IP, error) {
+	if parsed := net.ParseIP(value); parsed != nil {
+		if parsed.To4() != nil {
+			return nil, fmt.Errorf("not an IPv6 interface ID: %s", value)
+		}
+		return parsed, nil
+	}
+
+	mac, err := net.ParseMAC(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interface ID %q: want an IPv6 suffix like ::10 or a MAC address", value)
+	}
+
+	var eui64 []byte
+	switch len(mac) {
+	case 6:
+		eui64 = []byte{mac[0], mac[1], mac[2], 0xff, 0xfe, mac[3], mac[4], mac[5]}
+	case 8:
+		eui64 = append([]byte{}, mac...)
+	default:
+		return nil, fmt.Errorf("invalid interface ID %q: want a 48 or 64 bit MAC address", value)
+	}
+	// Modified EUI-64 flips the universal/local bit (RFC 4291, appendix A).
+	eui64[0] ^= 0x02
+
+	result := make(net.IP, net.IPv6len)
+	copy(result[8:], eui64)
+	return result, nil
+}
@@ -0,0 +1,68 @@
+package ip
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposeIPv6(t *testing.T) {
+	tests := []struct {
+		name         string
+		prefix       string
+		prefixLength int
+		suffix       string
+		want         string
+		wantErr      string
+	}{
+		{name: "/64", prefix: "2001:db8:1:2:aaaa:bbbb:cccc:dddd", prefixLength: 64, suffix: "::10", want: "2001:db8:1:2::10"},
+		{name: "/56", prefix: "2001:db8:1:2ff::1", prefixLength: 56, suffix: "0:0:0:5::10", want: "2001:db8:1:205::10"},
+		{name: "suffix high bits ignored", prefix: "2001:db8::1", prefixLength: 64, suffix: "fd00::1:2:3:4", want: "2001:db8::1:2:3:4"},
+		{name: "IPv4 prefix", prefix: "203.0.113.1", prefixLength: 64, suffix: "::1", wantErr: "not an IPv6 prefix: 203.0.113.1"},
+		{name: "bad length", prefix: "2001:db8::1", prefixLength: 129, suffix: "::1", wantErr: "invalid IPv6 prefix length: 129"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ComposeIPv6(net.ParseIP(tt.prefix), tt.prefixLength, net.ParseIP(tt.suffix))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestParseInterfaceID(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{value: "::10", want: "::10"},
+		{value: "::a:b:c:d", want: "::a:b:c:d"},
+		{value: "52:54:00:12:34:56", want: "::5054:ff:fe12:3456"},
+		{value: "00-1b-21-0a-0b-0c", want: "::21b:21ff:fe0a:b0c"},
+		{value: "02-00-00-ff-fe-00-00-01", want: "::ff:fe00:1"},
+		{value: "10.0.0.1", wantErr: "not an IPv6 interface ID: 10.0.0.1"},
+		{value: "nas", wantErr: `invalid interface ID "nas"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseInterfaceID(tt.value)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
@@ -0,0 +1,424 @@
+package ip
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRouterTimeout = 2 * time.Second
+	ssdpMulticastAddress = "239.255.255.250:1900"
+	natPMPPort           = 5351
+)
+
+// Service types offering GetExternalIPAddress, most common first.
+var igdServiceTypes = []string{
+	"urn:schemas-upnp-org:service:WANIPConnection:2",
+	"urn:schemas-upnp-org:service:WANIPConnection:1",
+	"urn:schemas-upnp-org:service:WANPPPConnection:1",
+}
+
+// RouterDetector asks the home router for its WAN address, without any
+// request leaving the local network. It discovers an Internet Gateway
+// Device with SSDP and calls GetExternalIPAddress over UPnP, falling back
+// to NAT-PMP and PCP on the default gateway. Routers only report IPv4.
+type RouterDetector struct {
+	client  *http.Client
+	gateway string
+	timeout time.Duration
+
+	ssdpAddress string
+	natPMPPort  int
+}
+
+// NewRouterDetector returns a detector asking the router. gateway is the
+// address NAT-PMP and PCP requests go to; empty means the gateway of the
+// default route. A zero timeout means 2 seconds per step.
+func NewRouterDetector(gateway string, timeout time.Duration) *RouterDetector {
+	if timeout <= 0 {
+		timeout = defaultRouterTimeout
+	}
+
+	return &RouterDetector{
+		client:      &http.Client{Transport: &http.Transport{Proxy: nil}},
+		gateway:     gateway,
+		timeout:     timeout,
+		ssdpAddress: ssdpMulticastAddress,
+		natPMPPort:  natPMPPort,
+	}
+}
+
+func (r *RouterDetector) GetIPv4() (net.IP, error) {
+	var errs []error
+
+	for _, method := range []struct {
+		name string
+		get  func() (net.IP, error)
+	}{
+		{"UPnP", r.upnpExternalIP},
+		{"NAT-PMP", r.natPMPExternalIP},
+		{"PCP", r.pcpExternalIP},
+	} {
+		ip, err := method.get()
+		if err == nil {
+			err = checkFamily(ip, false)
+		}
+		if err == nil && ClassifyAddress(ip) != ClassPublic {
+			err = fmt.Errorf("router reports %s WAN address %s, it is behind another NAT", ClassifyAddress(ip), ip)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", method.name, err))
+			continue
+		}
+
+		return ip, nil
+	}
+
+	return nil, fmt.Errorf("failed to get IPv4 from router: %w", errors.Join(errs...))
+}
+
+func (r *RouterDetector) GetIPv6() (net.IP, error) {
+	return nil, fmt.Errorf("routers only report their IPv4 WAN address")
+}
+
+// upnpExternalIP searches Internet Gateway Devices and asks each that
+// answers until one reports its external address.
+func (r *RouterDetector) upnpExternalIP() (net.IP, error) {
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	target, err := net.ResolveUDPAddr("udp4", r.ssdpAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, searchTarget := range []string{
+		"urn:schemas-upnp-org:device:InternetGatewayDevice:1",
+		"urn:schemas-upnp-org:device:InternetGatewayDevice:2",
+	} {
+		request := "M-SEARCH * HTTP/1.1\r\n" +
+			"HOST: " + ssdpMulticastAddress + "\r\n" +
+			"MAN: \"ssdp:discover\"\r\n" +
+			"MX: 1\r\n" +
+			"ST: " + searchTarget + "\r\n\r\n"
+		if _, err := conn.WriteTo([]byte(request), target); err != nil {
+			return nil, fmt.Errorf("failed to send SSDP search: %w", err)
+		}
+	}
+
+	var errs []error
+	tried := make(map[string]bool)
+	conn.SetReadDeadline(time.Now().Add(r.timeout))
+	buf := make([]byte, 2048)
+
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if len(errs) > 0 {
+				return nil, errors.Join(errs...)
+			}
+			return nil, fmt.Errorf("no gateway device found")
+		}
+
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+
+		location := resp.Header.Get("Location")
+		if location == "" || tried[location] {
+			continue
+		}
+		tried[location] = true
+
+		ip, err := r.igdExternalIP(location)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", location, err))
+			continue
+		}
+		return ip, nil
+	}
+}
+
+type upnpRoot struct {
+	URLBase string     `xml:"URLBase"`
+	Device  upnpDevice `xml:"device"`
+}
+
+type upnpDevice struct {
+	Services []upnpService `xml:"serviceList>service"`
+	Devices  []upnpDevice  `xml:"deviceList>device"`
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+// findService returns the first service of serviceType in the device tree.
+func (d upnpDevice) findService(serviceType string) (upnpService, bool) {
+	for _, service := range d.Services {
+		if service.ServiceType == serviceType {
+			return service, true
+		}
+	}
+	for _, device := range d.Devices {
+		if service, ok := device.findService(serviceType); ok {
+			return service, true
+		}
+	}
+	return upnpService{}, false
+}
+
+// igdExternalIP reads the device description at location and calls
+// GetExternalIPAddress on its WAN connection service.
+func (r *RouterDetector) igdExternalIP(location string) (net.IP, error) {
+	body, err := r.httpDo(http.MethodGet, location, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var root upnpRoot
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("invalid device description: %w", err)
+	}
+
+	base, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if root.URLBase != "" {
+		if base, err = url.Parse(root.URLBase); err != nil {
+			return nil, fmt.Errorf("invalid URLBase: %w", err)
+		}
+	}
+
+	for _, serviceType := range igdServiceTypes {
+		service, ok := root.Device.findService(serviceType)
+		if !ok {
+			continue
+		}
+
+		controlURL, err := base.Parse(service.ControlURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid control URL: %w", err)
+		}
+
+		envelope := `<?xml version="1.0"?>` +
+			`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+			`<s:Body><u:GetExternalIPAddress xmlns:u="` + serviceType + `"></u:GetExternalIPAddress></s:Body>` +
+			`</s:Envelope>`
+		headers := map[string]string{
+			"Content-Type": `text/xml; charset="utf-8"`,
+			"SOAPAction":   `"` + serviceType + `#GetExternalIPAddress"`,
+		}
+
+		body, err := r.httpDo(http.MethodPost, controlURL.String(), strings.NewReader(envelope), headers)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := xmlElementText(body, "NewExternalIPAddress")
+		if err != nil {
+			return nil, err
+		}
+
+		ip := net.ParseIP(strings.TrimSpace(value))
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address received: %s", value)
+		}
+		return ip, nil
+	}
+
+	return nil, fmt.Errorf("device has no WAN connection service")
+}
+
+func (r *RouterDetector) httpDo(method, target string, body io.Reader, headers map[string]string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gateway returned status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxEchoResponseSize))
+}
+
+// xmlElementText returns the text of the first element called name,
+// whatever its namespace.
+func xmlElementText(document []byte, name string) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("%s not found in response", name)
+		}
+
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == name {
+			var value string
+			if err := decoder.DecodeElement(&value, &start); err != nil {
+				return "", fmt.Errorf("invalid %s: %w", name, err)
+			}
+			return value, nil
+		}
+	}
+}
+
+// natPMPExternalIP sends a NAT-PMP external address request (RFC 6886).
+func (r *RouterDetector) natPMPExternalIP() (net.IP, error) {
+	response, err := r.exchangeGateway([]byte{0, 0}, func(response []byte) bool {
+		return len(response) >= 2 && response[0] == 0 && response[1] == 128
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response) < 12 {
+		return nil, fmt.Errorf("short NAT-PMP response")
+	}
+	if code := binary.BigEndian.Uint16(response[2:4]); code != 0 {
+		return nil, fmt.Errorf("gateway answered result code %d", code)
+	}
+
+	return net.IP(append([]byte{}, response[8:12]...)), nil
+}
+
+// pcpExternalIP asks a PCP server (RFC 6887) for a short-lived mapping of
+// an unused UDP port and reads the external address from the answer. The
+// mapping is deleted again right away.
+func (r *RouterDetector) pcpExternalIP() (net.IP, error) {
+	var nonce [12]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+
+	request := func(clientIP net.IP, lifetime uint32) []byte {
+		packet := make([]byte, 60)
+		packet[0] = 2 // version
+		packet[1] = 1 // MAP
+		binary.BigEndian.PutUint32(packet[4:8], lifetime)
+		copy(packet[8:24], clientIP.To16())
+		copy(packet[24:36], nonce[:])
+		packet[36] = 17                              // UDP
+		binary.BigEndian.PutUint16(packet[40:42], 9) // discard port
+		return packet
+	}
+
+	matches := func(response []byte) bool {
+		return len(response) >= 60 && response[0] == 2 && response[1] == 128|1 && bytes.Equal(response[24:36], nonce[:])
+	}
+
+	var clientIP net.IP
+	response, err := r.exchangeGatewayFrom(func(local net.IP) []byte {
+		clientIP = local
+		return request(local, 60)
+	}, matches)
+	if err != nil {
+		return nil, err
+	}
+
+	if code := response[3]; code != 0 {
+		return nil, fmt.Errorf("gateway answered result code %d", code)
+	}
+	ip := net.IP(append([]byte{}, response[44:60]...))
+
+	// Best effort: a lifetime of zero deletes the mapping.
+	r.exchangeGatewayFrom(func(net.IP) []byte { return request(clientIP, 0) }, matches)
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, nil
+	}
+	return ip, nil
+}
+
+func (r *RouterDetector) exchangeGateway(request []byte, matches func([]byte) bool) ([]byte, error) {
+	return r.exchangeGatewayFrom(func(net.IP) []byte { return request }, matches)
+}
+
+// exchangeGatewayFrom sends the request built for the local address of the
+// connection to the gateway and waits for a matching response, resending
+// it with the doubling intervals of NAT-PMP.
+func (r *RouterDetector) exchangeGatewayFrom(build func(local net.IP) []byte, matches func([]byte) bool) ([]byte, error) {
+	gateway := r.gateway
+	if gateway == "" {
+		detected, err := defaultGateway()
+		if err != nil {
+			return nil, err
+		}
+		gateway = detected.String()
+	}
+
+	conn, err := net.Dial("udp4", net.JoinHostPort(gateway, fmt.Sprint(r.natPMPPort)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	request := build(conn.LocalAddr().(*net.UDPAddr).IP)
+	deadline := time.Now().Add(r.timeout)
+	buf := make([]byte, 1100)
+
+	for wait := 250 * time.Millisecond; time.Now().Before(deadline); wait *= 2 {
+		if _, err := conn.Write(request); err != nil {
+			return nil, err
+		}
+
+		conn.SetReadDeadline(minTime(time.Now().Add(wait), deadline))
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break
+				}
+				return nil, err
+			}
+			if matches(buf[:n]) {
+				return append([]byte{}, buf[:n]...), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no answer from gateway %s", gateway)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func (r *RouterDetector) Name() string {
+	return "Router (UPnP/NAT-PMP)"
+}
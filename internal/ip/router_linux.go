@@ -0,0 +1,48 @@
+package ip
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultGateway reads the gateway of the IPv4 default route from
+// /proc/net/route.
+func defaultGateway() (net.IP, error) {
+	file, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routes: %w", err)
+	}
+	defer file.Close()
+
+	return parseProcRoute(bufio.NewScanner(file))
+}
+
+// parseProcRoute finds the default route in lines like
+// "eth0 00000000 0101A8C0 0003 0 0 0 00000000 0 0 0": interface,
+// destination and gateway, in host byte order.
+func parseProcRoute(scanner *bufio.Scanner) (net.IP, error) {
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[2], 16, 32)
+		if err != nil {
+			continue
+		}
+
+		gateway := make(net.IP, 4)
+		binary.NativeEndian.PutUint32(gateway, uint32(value))
+		if !gateway.IsUnspecified() {
+			return gateway, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no default gateway found")
+}
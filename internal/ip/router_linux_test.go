@@ -0,0 +1,25 @@
+package ip
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcRoute(t *testing.T) {
+	input := strings.Join([]string{
+		"Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT",
+		"eth0\t0001A8C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0",
+		"eth0\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0",
+	}, "\n")
+
+	gateway, err := parseProcRoute(bufio.NewScanner(strings.NewReader(input)))
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.1", gateway.String())
+
+	_, err = parseProcRoute(bufio.NewScanner(strings.NewReader(input[:strings.LastIndex(input, "\n")])))
+	assert.EqualError(t, err, "no default gateway found")
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package ip
+
+import (
+	"fmt"
+	"net"
+)
+
+// defaultGateway is only implemented on Linux; elsewhere the gateway has to
+// be configured.
+func defaultGateway() (net.IP, error) {
+	return nil, fmt.Errorf("cannot find the default gateway on this system, configure it")
+}
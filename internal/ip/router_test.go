@@ -0,0 +1,206 @@
+package ip
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIGD is an Internet Gateway Device on loopback: an SSDP responder
+// pointing at an HTTP server with the device description and control URL.
+func fakeIGD(t *testing.T, externalIP string) string {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rootDesc.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+    <serviceList>
+      <service>
+        <serviceType>urn:schemas-upnp-org:service:Layer3Forwarding:1</serviceType>
+        <controlURL>/ctl/L3F</controlURL>
+      </service>
+    </serviceList>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType>
+        <deviceList>
+          <device>
+            <deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:1</deviceType>
+            <serviceList>
+              <service>
+                <serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType>
+                <controlURL>/ctl/IPConn</controlURL>
+              </service>
+            </serviceList>
+          </device>
+        </deviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`)
+	})
+	mux.HandleFunc("POST /ctl/IPConn", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("SOAPAction") != `"urn:schemas-upnp-org:service:WANIPConnection:1#GetExternalIPAddress"` ||
+			!bytes.Contains(body, []byte("GetExternalIPAddress")) {
+			http.Error(w, "invalid action", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+  <s:Body>
+    <u:GetExternalIPAddressResponse xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1">
+      <NewExternalIPAddress>%s</NewExternalIPAddress>
+    </u:GetExternalIPAddressResponse>
+  </s:Body>
+</s:Envelope>`, externalIP)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buf[:n])))
+			if err != nil || req.Method != "M-SEARCH" || !strings.Contains(req.Header.Get("St"), "InternetGatewayDevice") {
+				continue
+			}
+
+			// A device without description must not stop the search.
+			conn.WriteTo([]byte("HTTP/1.1 200 OK\r\nST: upnp:rootdevice\r\nLOCATION: "+server.URL+"/missing.xml\r\n\r\n"), peer)
+			conn.WriteTo([]byte("HTTP/1.1 200 OK\r\nST: "+req.Header.Get("St")+"\r\nLOCATION: "+server.URL+"/rootDesc.xml\r\n\r\n"), peer)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// fakeNATGateway answers NAT-PMP external address requests and PCP MAP
+// requests on loopback.
+type fakeNATGateway struct {
+	externalIP net.IP
+	natPMP     bool
+	pcp        bool
+	pcpDeletes atomic.Int32
+}
+
+func (g *fakeNATGateway) serve(t *testing.T) int {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1100)
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			switch {
+			case g.natPMP && n == 2 && buf[0] == 0 && buf[1] == 0:
+				response := make([]byte, 12)
+				response[1] = 128
+				binary.BigEndian.PutUint32(response[4:8], 1234)
+				copy(response[8:12], g.externalIP.To4())
+				conn.WriteTo(response, peer)
+			case g.pcp && n == 60 && buf[0] == 2 && buf[1] == 1:
+				lifetime := binary.BigEndian.Uint32(buf[4:8])
+				if lifetime == 0 {
+					g.pcpDeletes.Add(1)
+				}
+				response := make([]byte, 60)
+				copy(response, buf[:60])
+				response[0] = 2
+				response[1] = 128 | 1
+				response[3] = 0
+				copy(response[44:60], g.externalIP.To16())
+				conn.WriteTo(response, peer)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func newTestRouterDetector(ssdpAddress string, natPMPPort int) *RouterDetector {
+	detector := NewRouterDetector("127.0.0.1", 300*time.Millisecond)
+	detector.ssdpAddress = ssdpAddress
+	detector.natPMPPort = natPMPPort
+	return detector
+}
+
+// silentAddress returns a loopback address nobody answers on.
+func silentAddress(t *testing.T) (string, int) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn.LocalAddr().String(), conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestRouterDetector_UPnP(t *testing.T) {
+	_, silentPort := silentAddress(t)
+	detector := newTestRouterDetector(fakeIGD(t, "93.184.216.34"), silentPort)
+
+	ip, err := detector.GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "93.184.216.34", ip.String())
+}
+
+func TestRouterDetector_NATPMP(t *testing.T) {
+	silent, _ := silentAddress(t)
+	gateway := &fakeNATGateway{externalIP: net.ParseIP("93.184.216.35"), natPMP: true}
+	detector := newTestRouterDetector(silent, gateway.serve(t))
+
+	ip, err := detector.GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "93.184.216.35", ip.String())
+}
+
+func TestRouterDetector_PCP(t *testing.T) {
+	silent, _ := silentAddress(t)
+	gateway := &fakeNATGateway{externalIP: net.ParseIP("93.184.216.36"), pcp: true}
+	detector := newTestRouterDetector(silent, gateway.serve(t))
+
+	ip, err := detector.GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "93.184.216.36", ip.String())
+	assert.Equal(t, int32(1), gateway.pcpDeletes.Load())
+}
+
+func TestRouterDetector_BehindCGNAT(t *testing.T) {
+	silent, _ := silentAddress(t)
+	gateway := &fakeNATGateway{externalIP: net.ParseIP("100.64.1.2"), natPMP: true}
+	detector := newTestRouterDetector(silent, gateway.serve(t))
+
+	_, err := detector.GetIPv4()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "UPnP: no gateway device found")
+	assert.Contains(t, err.Error(), "NAT-PMP: router reports shared WAN address 100.64.1.2, it is behind another NAT")
+	assert.Contains(t, err.Error(), "PCP: no answer from gateway 127.0.0.1")
+
+	_, err = detector.GetIPv6()
+	assert.EqualError(t, err, "routers only report their IPv4 WAN address")
+}
@@ -0,0 +1,252 @@
+package ip
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// STUN message constants from RFC 5389.
+const (
+	stunBindingRequest       = 0x0001
+	stunBindingSuccess       = 0x0101
+	stunBindingError         = 0x0111
+	stunMagicCookie          = 0x2112A442
+	stunHeaderSize           = 20
+	stunAttrMappedAddress    = 0x0001
+	stunAttrXORMappedAddress = 0x0020
+	stunFamilyIPv4           = 0x01
+	stunFamilyIPv6           = 0x02
+)
+
+const (
+	defaultSTUNTimeout = 3 * time.Second
+	stunAttempts       = 3
+)
+
+var DefaultSTUNServers = []string{
+	"stun.cloudflare.com:3478",
+	"stun.l.google.com:19302",
+}
+
+// STUNDetector discovers the public address with STUN Binding requests, the
+// way WebRTC peers find their server-reflexive address. It works on
+// networks that block HTTP to echo services but allow outbound UDP.
+type STUNDetector struct {
+	servers []string
+	timeout time.Duration
+}
+
+// NewSTUNDetector returns a detector asking servers ("host:port") in order.
+// Without servers the public Cloudflare and Google servers are used; a zero
+// timeout means 3 seconds per server.
+func NewSTUNDetector(servers []string, timeout time.Duration) *STUNDetector {
+	if len(servers) == 0 {
+		servers = DefaultSTUNServers
+	}
+	if timeout <= 0 {
+		timeout = defaultSTUNTimeout
+	}
+
+	return &STUNDetector{servers: servers, timeout: timeout}
+}
+
+func (s *STUNDetector) GetIPv4() (net.IP, error) {
+	ip, err := s.query("udp4")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPv4 via STUN: %w", err)
+	}
+	return ip, nil
+}
+
+func (s *STUNDetector) GetIPv6() (net.IP, error) {
+	ip, err := s.query("udp6")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPv6 via STUN: %w", err)
+	}
+	return ip, nil
+}
+
+func (s *STUNDetector) query(network string) (net.IP, error) {
+	var errs []error
+
+	for _, server := range s.servers {
+		ip, err := s.bind(network, server)
+		if err == nil {
+			err = checkFamily(ip, network == "udp6")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", server, err))
+			continue
+		}
+
+		return ip, nil
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// bind sends a Binding request to server and returns the mapped address of
+// the response. The request is retransmitted a few times within the timeout,
+// since UDP may drop it.
+func (s *STUNDetector) bind(network, server string) (net.IP, error) {
+	conn, err := net.DialTimeout(network, server, s.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	request, transactionID, err := newSTUNBindingRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(s.timeout)
+	buf := make([]byte, 1500)
+
+	for attempt := 1; attempt <= stunAttempts; attempt++ {
+		if _, err := conn.Write(request); err != nil {
+			return nil, err
+		}
+
+		attemptDeadline := time.Now().Add(s.timeout / stunAttempts)
+		if attempt == stunAttempts || attemptDeadline.After(deadline) {
+			attemptDeadline = deadline
+		}
+		conn.SetReadDeadline(attemptDeadline)
+
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() && attempt < stunAttempts {
+					break
+				}
+				return nil, err
+			}
+
+			ip, err := parseSTUNBindingResponse(buf[:n], transactionID)
+			if errors.Is(err, errSTUNUnrelated) {
+				continue
+			}
+			return ip, err
+		}
+	}
+
+	return nil, fmt.Errorf("no response")
+}
+
+func newSTUNBindingRequest() ([]byte, []byte, error) {
+	request := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(request[0:2], stunBindingRequest)
+	binary.BigEndian.PutUint16(request[2:4], 0)
+	binary.BigEndian.PutUint32(request[4:8], stunMagicCookie)
+
+	transactionID := request[8:20]
+	if _, err := rand.Read(transactionID); err != nil {
+		return nil, nil, fmt.Errorf("failed to create transaction ID: %w", err)
+	}
+
+	return request, transactionID, nil
+}
+
+// errSTUNUnrelated marks packets that are not the response to our request,
+// such as late answers to an earlier retransmission.
+var errSTUNUnrelated = errors.New("unrelated STUN message")
+
+func parseSTUNBindingResponse(msg, transactionID []byte) (net.IP, error) {
+	if len(msg) < stunHeaderSize ||
+		binary.BigEndian.Uint32(msg[4:8]) != stunMagicCookie ||
+		!bytes.Equal(msg[8:20], transactionID) {
+		return nil, errSTUNUnrelated
+	}
+
+	messageType := binary.BigEndian.Uint16(msg[0:2])
+	length := int(binary.BigEndian.Uint16(msg[2:4]))
+	if stunHeaderSize+length > len(msg) {
+		return nil, fmt.Errorf("truncated STUN message")
+	}
+
+	switch messageType {
+	case stunBindingSuccess:
+	case stunBindingError:
+		return nil, fmt.Errorf("server returned a STUN error response")
+	default:
+		return nil, errSTUNUnrelated
+	}
+
+	var mapped net.IP
+	attributes := msg[stunHeaderSize : stunHeaderSize+length]
+	for len(attributes) >= 4 {
+		attrType := binary.BigEndian.Uint16(attributes[0:2])
+		attrLength := int(binary.BigEndian.Uint16(attributes[2:4]))
+		if 4+attrLength > len(attributes) {
+			return nil, fmt.Errorf("truncated STUN attribute")
+		}
+		value := attributes[4 : 4+attrLength]
+
+		switch attrType {
+		case stunAttrXORMappedAddress:
+			return parseSTUNAddress(value, msg[4:20])
+		case stunAttrMappedAddress:
+			// Servers implementing only RFC 3489 send the plain address.
+			ip, err := parseSTUNAddress(value, nil)
+			if err != nil {
+				return nil, err
+			}
+			mapped = ip
+		}
+
+		// Attributes are padded to a multiple of four bytes.
+		padded := (attrLength + 3) &^ 3
+		if 4+padded > len(attributes) {
+			break
+		}
+		attributes = attributes[4+padded:]
+	}
+
+	if mapped == nil {
+		return nil, fmt.Errorf("STUN response has no mapped address")
+	}
+	return mapped, nil
+}
+
+// parseSTUNAddress decodes a (XOR-)MAPPED-ADDRESS value. xorKey is the magic
+// cookie followed by the transaction ID, or nil for a plain address.
+func parseSTUNAddress(value, xorKey []byte) (net.IP, error) {
+	if len(value) < 4 {
+		return nil, fmt.Errorf("invalid STUN address attribute")
+	}
+
+	var size int
+	switch value[1] {
+	case stunFamilyIPv4:
+		size = net.IPv4len
+	case stunFamilyIPv6:
+		size = net.IPv6len
+	default:
+		return nil, fmt.Errorf("unknown STUN address family %d", value[1])
+	}
+
+	if len(value) < 4+size {
+		return nil, fmt.Errorf("invalid STUN address attribute")
+	}
+
+	ip := make(net.IP, size)
+	copy(ip, value[4:4+size])
+	if xorKey != nil {
+		for i := range ip {
+			ip[i] ^= xorKey[i]
+		}
+	}
+
+	return ip, nil
+}
+
+func (s *STUNDetector) Name() string {
+	return "STUN"
+}
@@ -0,0 +1,157 @@
+package ip
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stunResponder answers Binding requests with a fixed mapped address.
+type stunResponder struct {
+	mapped   net.IP
+	plain    bool
+	errorOut bool
+	drop     int
+}
+
+func (r *stunResponder) serve(t *testing.T, network, address string) string {
+	conn, err := net.ListenPacket(network, address)
+	if err != nil {
+		t.Skipf("cannot listen on %s: %v", address, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < stunHeaderSize || binary.BigEndian.Uint16(buf[0:2]) != stunBindingRequest {
+				continue
+			}
+			if r.drop > 0 {
+				r.drop--
+				continue
+			}
+
+			// A stray packet with another transaction ID must be ignored.
+			stray := r.response(make([]byte, 12))
+			conn.WriteTo(stray, peer)
+
+			conn.WriteTo(r.response(buf[8:20]), peer)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func (r *stunResponder) response(transactionID []byte) []byte {
+	header := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint32(header[4:8], stunMagicCookie)
+	copy(header[8:20], transactionID)
+
+	if r.errorOut {
+		binary.BigEndian.PutUint16(header[0:2], stunBindingError)
+		return header
+	}
+	binary.BigEndian.PutUint16(header[0:2], stunBindingSuccess)
+
+	family, ip := byte(stunFamilyIPv6), r.mapped.To16()
+	if ip4 := r.mapped.To4(); ip4 != nil {
+		family, ip = stunFamilyIPv4, ip4
+	}
+
+	value := make([]byte, 4+len(ip))
+	value[1] = family
+	binary.BigEndian.PutUint16(value[2:4], 54321^(stunMagicCookie>>16))
+	copy(value[4:], ip)
+
+	attrType := uint16(stunAttrMappedAddress)
+	if !r.plain {
+		attrType = stunAttrXORMappedAddress
+		for i := range ip {
+			value[4+i] ^= header[4+i]
+		}
+	}
+
+	// An unknown attribute with padding precedes the address.
+	software := []byte{0x80, 0x22, 0x00, 0x03, 'f', 'a', 'k', 0x00}
+	attr := make([]byte, 4)
+	binary.BigEndian.PutUint16(attr[0:2], attrType)
+	binary.BigEndian.PutUint16(attr[2:4], uint16(len(value)))
+
+	msg := append(header, software...)
+	msg = append(msg, attr...)
+	msg = append(msg, value...)
+	binary.BigEndian.PutUint16(msg[2:4], uint16(len(msg)-stunHeaderSize))
+	return msg
+}
+
+func TestSTUNDetector_IPv4(t *testing.T) {
+	responder := &stunResponder{mapped: net.ParseIP("203.0.113.7")}
+	server := responder.serve(t, "udp4", "127.0.0.1:0")
+
+	detector := NewSTUNDetector([]string{server}, time.Second)
+	ip, err := detector.GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.7", ip.String())
+}
+
+func TestSTUNDetector_IPv6(t *testing.T) {
+	responder := &stunResponder{mapped: net.ParseIP("2001:db8::7")}
+	server := responder.serve(t, "udp6", "[::1]:0")
+
+	detector := NewSTUNDetector([]string{server}, time.Second)
+	ip, err := detector.GetIPv6()
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::7", ip.String())
+}
+
+func TestSTUNDetector_PlainMappedAddress(t *testing.T) {
+	responder := &stunResponder{mapped: net.ParseIP("203.0.113.8"), plain: true}
+	server := responder.serve(t, "udp4", "127.0.0.1:0")
+
+	ip, err := NewSTUNDetector([]string{server}, time.Second).GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.8", ip.String())
+}
+
+func TestSTUNDetector_Retransmits(t *testing.T) {
+	responder := &stunResponder{mapped: net.ParseIP("203.0.113.9"), drop: 1}
+	server := responder.serve(t, "udp4", "127.0.0.1:0")
+
+	ip, err := NewSTUNDetector([]string{server}, 900*time.Millisecond).GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.9", ip.String())
+}
+
+func TestSTUNDetector_FallsBackToNextServer(t *testing.T) {
+	failing := (&stunResponder{errorOut: true}).serve(t, "udp4", "127.0.0.1:0")
+	wrongFamily := (&stunResponder{mapped: net.ParseIP("2001:db8::7")}).serve(t, "udp4", "127.0.0.1:0")
+	working := (&stunResponder{mapped: net.ParseIP("203.0.113.7")}).serve(t, "udp4", "127.0.0.1:0")
+
+	ip, err := NewSTUNDetector([]string{failing, wrongFamily, working}, time.Second).GetIPv4()
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.7", ip.String())
+
+	_, err = NewSTUNDetector([]string{failing, wrongFamily}, time.Second).GetIPv4()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), failing+": server returned a STUN error response")
+	assert.Contains(t, err.Error(), wrongFamily+": received non-IPv4 address: 2001:db8::7")
+}
+
+func TestSTUNDetector_NoResponse(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = NewSTUNDetector([]string{conn.LocalAddr().String()}, 150*time.Millisecond).GetIPv4()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "i/o timeout")
+}
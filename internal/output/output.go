@@ -0,0 +1,94 @@
+// Package output writes the machine-readable documents of the --output json
+// and --output yaml modes.
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// ParseFormat parses the value of --output, ignoring case.
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(value))); format {
+	case FormatText, FormatJSON, FormatYAML:
+		return format, nil
+	case "":
+		return FormatText, nil
+	default:
+		return "", fmt.Errorf("unsupported output format: %s (want text, json or yaml)", value)
+	}
+}
+
+// ErrReported marks failures that the result document already describes,
+// so structured output does not repeat them as an error document.
+var ErrReported = errors.New("see the result for details")
+
+// Error is the document written when a command fails.
+type Error struct {
+	Error string `json:"error" yaml:"error"`
+}
+
+// Printer writes documents to w. Several documents written by one printer
+// form a stream: consecutive JSON values, or YAML documents separated by
+// "---".
+type Printer struct {
+	w      io.Writer
+	format Format
+	json   *json.Encoder
+	yaml   *yaml.Encoder
+}
+
+func NewPrinter(w io.Writer, format Format) *Printer {
+	p := &Printer{w: w, format: format}
+
+	switch format {
+	case FormatJSON:
+		p.json = json.NewEncoder(w)
+		p.json.SetIndent("", "  ")
+	case FormatYAML:
+		p.yaml = yaml.NewEncoder(w)
+		p.yaml.SetIndent(2)
+	}
+
+	return p
+}
+
+func (p *Printer) Format() Format {
+	return p.format
+}
+
+// Writer returns the writer documents are written to. In text format,
+// commands write their human-readable output to it.
+func (p *Printer) Writer() io.Writer {
+	return p.w
+}
+
+// Structured reports whether documents are written, i.e. the format is not
+// text. Commands print human-readable text otherwise.
+func (p *Printer) Structured() bool {
+	return p.format != FormatText
+}
+
+func (p *Printer) Print(v any) error {
+	switch p.format {
+	case FormatJSON:
+		return p.json.Encode(v)
+	case FormatYAML:
+		return p.yaml.Encode(v)
+	default:
+		return fmt.Errorf("cannot print a document in %s format", p.format)
+	}
+}
@@ -0,0 +1,81 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yy4382/dns-set/pkg/dnsset"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    Format
+		expectError bool
+	}{
+		{value: "", expected: FormatText},
+		{value: "text", expected: FormatText},
+		{value: "json", expected: FormatJSON},
+		{value: "yaml", expected: FormatYAML},
+		{value: "JSON", expected: FormatJSON},
+		{value: " Yaml ", expected: FormatYAML},
+		{value: "xml", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			format, err := ParseFormat(tt.value)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+}
+
+func TestPrinter_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	printer := NewPrinter(&buf, FormatJSON)
+
+	require.NoError(t, printer.Print(dnsset.RecordResult{
+		Domain: "app.example.com",
+		Type:   dnsset.RecordTypeA,
+		Action: dnsset.ActionUpdated,
+		Old:    []dnsset.Record{{ID: "1", Name: "app.example.com", Type: dnsset.RecordTypeA, Content: "198.51.100.1", TTL: 1}},
+		New:    &dnsset.Record{ID: "1", Name: "app.example.com", Type: dnsset.RecordTypeA, Content: "203.0.113.7", TTL: 1},
+	}))
+
+	assert.JSONEq(t, `{
+		"domain": "app.example.com",
+		"type": "A",
+		"action": "updated",
+		"old": [{"id": "1", "name": "app.example.com", "type": "A", "content": "198.51.100.1", "ttl": 1, "proxied": false}],
+		"new": {"id": "1", "name": "app.example.com", "type": "A", "content": "203.0.113.7", "ttl": 1, "proxied": false}
+	}`, buf.String())
+}
+
+func TestPrinter_YAMLStream(t *testing.T) {
+	var buf bytes.Buffer
+	printer := NewPrinter(&buf, FormatYAML)
+
+	require.NoError(t, printer.Print(dnsset.Address{Type: dnsset.RecordTypeA, IP: "203.0.113.7", Source: "External API (ip.sb)"}))
+	require.NoError(t, printer.Print(Error{Error: "no zone found"}))
+
+	assert.Equal(t, `type: A
+ip: 203.0.113.7
+source: External API (ip.sb)
+---
+error: no zone found
+`, buf.String())
+}
+
+func TestPrinter_TextIsNotStructured(t *testing.T) {
+	printer := NewPrinter(&bytes.Buffer{}, FormatText)
+
+	assert.False(t, printer.Structured())
+	assert.Error(t, printer.Print(Error{Error: "boom"}))
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/yy4382/dns-set/internal/app"
+	"github.com/yy4382/dns-set/pkg/dnsset"
+)
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type domainsResponse struct {
+	Source  string   `json:"source"`
+	Domains []string `json:"domains"`
+}
+
+type addressesResponse struct {
+	Addresses []dnsset.Address `json:"addresses"`
+}
+
+func (s *Server) registerAPI() {
+	s.mux.HandleFunc("GET /api/v1/domains", s.requireToken(s.handleListDomains))
+	s.mux.HandleFunc("GET /api/v1/addresses", s.requireToken(s.handleDetectAddresses))
+	s.mux.HandleFunc("GET /api/v1/sync", s.requireToken(s.handleLastSync))
+	s.mux.HandleFunc("POST /api/v1/sync", s.requireToken(s.handleSync))
+	s.mux.HandleFunc("POST /api/v1/records", s.requireToken(s.handleUpdateRecord))
+}
+
+func (s *Server) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Server.API.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="dns-set"`)
+			writeJSON(w, http.StatusUnauthorized, apiError{Error: "invalid or missing API token"})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (s *Server) handleListDomains(w http.ResponseWriter, r *http.Request) {
+	source, err := app.NewDomainSource(s.config)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		return
+	}
+
+	domains, err := source.GetDomains()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: fmt.Sprintf("failed to get domains: %v", err)})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, domainsResponse{Source: source.Name(), Domains: domains})
+}
+
+func (s *Server) handleDetectAddresses(w http.ResponseWriter, r *http.Request) {
+	spec, err := app.SpecFromConfig(s.config, s.provider)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		return
+	}
+
+	response := addressesResponse{Addresses: []dnsset.Address{}}
+	for _, recordType := range spec.RecordTypes {
+		address, _ := dnsset.DetectAddress(spec.Detector, recordType)
+		response.Addresses = append(response.Addresses, address)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleLastSync(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	last := s.lastSync
+	s.mu.Unlock()
+
+	if last == nil {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "no sync has run yet"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, last)
+}
+
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	result, err := s.sync(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleUpdateRecord points a single record at the content of the request
+// body. Only the domains of the configured sync can be updated. Without
+// content the address is detected like in a full sync, and a zero TTL falls
+// back to the configured default.
+func (s *Server) handleUpdateRecord(w http.ResponseWriter, r *http.Request) {
+	var record dnsset.Record
+	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if record.Name == "" {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "record name is required"})
+		return
+	}
+
+	recordType, err := dnsset.ParseRecordType(string(record.Type))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+
+	spec, err := app.SpecFromConfig(s.config, s.provider)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		return
+	}
+	if !slices.ContainsFunc(spec.Domains, func(domain string) bool { return strings.EqualFold(domain, record.Name) }) {
+		writeJSON(w, http.StatusForbidden, apiError{Error: fmt.Sprintf("%s is not one of the configured domains", record.Name)})
+		return
+	}
+
+	var target net.IP
+	if record.Content != "" {
+		target = net.ParseIP(record.Content)
+		if target == nil || (target.To4() != nil) != (recordType == dnsset.RecordTypeA) {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("invalid %s content: %s", recordType, record.Content)})
+			return
+		}
+	} else {
+		detector, err := app.NewDetector(s.config, s.config.Sync.IPDetector)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+			return
+		}
+
+		address, detected := dnsset.DetectAddress(detector, recordType)
+		if detected == nil {
+			writeJSON(w, http.StatusBadGateway, apiError{Error: fmt.Sprintf("failed to get %s address: %s", recordType, address.Error)})
+			return
+		}
+		target = detected
+	}
+
+	ttl := s.config.Preferences.DefaultTTL
+	if record.TTL != 0 {
+		ttl = &record.TTL
+	}
+
+	result := dnsset.UpdateRecord(s.provider, record.Name, recordType, target, ttl, record.Proxied)
+	s.logf(&result, "Record update for %s %s via API: %s", recordType, record.Name, result.Action)
+
+	status := http.StatusOK
+	if result.Action == dnsset.ActionFailed {
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, result)
+}
+
+// sync runs the configured sync and remembers its result. Concurrent
+// requests are serialized so only one sync talks to the provider at a time.
+func (s *Server) sync(ctx context.Context) (*dnsset.Result, error) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	spec, err := app.SpecFromConfig(s.config, s.provider)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := dnsset.Sync(ctx, spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync DNS records: %w", err)
+	}
+
+	s.mu.Lock()
+	s.lastSync = result
+	s.mu.Unlock()
+
+	s.logf(nil, "Synced %d records, %d failed", len(result.Records), result.Failed())
+
+	return result, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yy4382/dns-set/internal/dns"
+	"github.com/yy4382/dns-set/pkg/dnsset"
+)
+
+func apiRequest(srv *Server, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer api-token")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPI_RequiresToken(t *testing.T) {
+	srv := newTestServer(&fakeProvider{})
+
+	for _, header := range []string{"", "Bearer wrong", "api-token"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/domains", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+
+		srv.Handler().ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.JSONEq(t, `{"error":"invalid or missing API token"}`, rec.Body.String())
+	}
+}
+
+func TestAPI_ListDomains(t *testing.T) {
+	srv := newTestServer(&fakeProvider{})
+
+	rec := apiRequest(srv, http.MethodGet, "/api/v1/domains", "")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"source":"Config","domains":["app.example.com","api.example.com"]}`, rec.Body.String())
+}
+
+func TestAPI_UpdateRecord(t *testing.T) {
+	provider := &fakeProvider{}
+	srv := newTestServer(provider)
+
+	rec := apiRequest(srv, http.MethodPost, "/api/v1/records", `{"name":"app.example.com","type":"AAAA","content":"2001:db8::1","proxied":true}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var result dnsset.RecordResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, dns.ActionCreated, result.Action)
+	require.NotNil(t, result.New)
+	assert.Equal(t, "2001:db8::1", result.New.Content)
+	assert.True(t, result.New.Proxied)
+
+	rec = apiRequest(srv, http.MethodPost, "/api/v1/records", `{"name":"app.example.com","type":"AAAA","content":"2001:db8::1","proxied":true}`)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, dns.ActionUnchanged, result.Action)
+	assert.Len(t, provider.updates, 1)
+}
+
+func TestAPI_UpdateRecord_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed json", body: `{`},
+		{name: "missing name", body: `{"type":"A","content":"192.0.2.1"}`},
+		{name: "unsupported type", body: `{"name":"app.example.com","type":"CNAME","content":"example.com"}`},
+		{name: "family mismatch", body: `{"name":"app.example.com","type":"A","content":"2001:db8::1"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(&fakeProvider{})
+
+			rec := apiRequest(srv, http.MethodPost, "/api/v1/records", tt.body)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestAPI_UpdateRecord_UnconfiguredDomain(t *testing.T) {
+	provider := &fakeProvider{}
+	srv := newTestServer(provider)
+
+	rec := apiRequest(srv, http.MethodPost, "/api/v1/records", `{"name":"other.example.com","type":"A","content":"192.0.2.1"}`)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.JSONEq(t, `{"error":"other.example.com is not one of the configured domains"}`, rec.Body.String())
+	assert.Empty(t, provider.updates)
+}
+
+func TestAPI_LastSync(t *testing.T) {
+	srv := newTestServer(&fakeProvider{})
+
+	rec := apiRequest(srv, http.MethodGet, "/api/v1/sync", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = apiRequest(srv, http.MethodPost, "/api/v1/sync", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var result dnsset.Result
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Len(t, result.Records, 2)
+
+	last := apiRequest(srv, http.MethodGet, "/api/v1/sync", "")
+	assert.Equal(t, http.StatusOK, last.Code)
+	assert.JSONEq(t, rec.Body.String(), last.Body.String())
+}
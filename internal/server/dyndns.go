@@ -0,0 +1,186 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/yy4382/dns-set/pkg/dnsset"
+)
+
+// Return codes of the dyndns2 protocol, as understood by ddclient, OpenWrt,
+// FritzBox, pfSense and most other routers.
+const (
+	dyndnsGood     = "good"
+	dyndnsNoChange = "nochg"
+	dyndnsBadAuth  = "badauth"
+	dyndnsNotFQDN  = "notfqdn"
+	dyndnsNoHost   = "nohost"
+	dyndnsNumHost  = "numhost"
+	dyndnsDNSError = "dnserr"
+	dyndnsFatal    = "911"
+)
+
+const maxDynDNSHostnames = 20
+
+func (s *Server) handleDynDNSUpdate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if !s.authenticateDynDNS(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="dns-set"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintln(w, dyndnsBadAuth)
+		return
+	}
+
+	query := r.URL.Query()
+
+	hostnames := splitList(query.Get("hostname"))
+	if len(hostnames) == 0 {
+		fmt.Fprintln(w, dyndnsNotFQDN)
+		return
+	}
+	if len(hostnames) > maxDynDNSHostnames {
+		fmt.Fprintln(w, dyndnsNumHost)
+		return
+	}
+
+	ips, err := dyndnsAddresses(r)
+	if err != nil {
+		s.logf(nil, "Rejected dyndns update: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, dyndnsFatal)
+		return
+	}
+
+	for _, hostname := range hostnames {
+		fmt.Fprintln(w, s.updateDynDNSHostname(hostname, ips))
+	}
+}
+
+func (s *Server) updateDynDNSHostname(hostname string, ips []net.IP) string {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	if !strings.Contains(hostname, ".") {
+		return dyndnsNotFQDN
+	}
+
+	if !hostnameAllowed(hostname, s.config.Server.DynDNS.Hostnames) {
+		return dyndnsNoHost
+	}
+
+	code := dyndnsNoChange
+	addresses := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		recordType := dnsset.RecordTypeAAAA
+		if ip.To4() != nil {
+			recordType = dnsset.RecordTypeA
+		}
+
+		result := dnsset.UpdateRecord(s.provider, hostname, recordType, ip, s.config.Preferences.DefaultTTL, s.config.Server.DynDNS.Proxied)
+		switch result.Action {
+		case dnsset.ActionFailed:
+			s.logf(&result, "Failed to update %s record for %s: %s", recordType, hostname, result.Error)
+			return dyndnsDNSError
+		case dnsset.ActionUnchanged:
+			s.logf(&result, "%s record for %s is already up to date", recordType, hostname)
+		default:
+			s.logf(&result, "Updated %s record for %s to %s", recordType, hostname, ip)
+			code = dyndnsGood
+		}
+		addresses = append(addresses, ip.String())
+	}
+
+	return fmt.Sprintf("%s %s", code, strings.Join(addresses, ","))
+}
+
+func (s *Server) authenticateDynDNS(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	for _, user := range s.config.Server.DynDNS.Users {
+		usernameMatch := subtle.ConstantTimeCompare([]byte(username), []byte(user.Username)) == 1
+		passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(user.Password)) == 1
+		if usernameMatch && passwordMatch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dyndnsAddresses collects the addresses to publish from the myip and
+// myipv6 parameters. Some clients send both families comma-separated in
+// myip. Without either parameter the address of the client is used.
+func dyndnsAddresses(r *http.Request) ([]net.IP, error) {
+	query := r.URL.Query()
+	values := append(splitList(query.Get("myip")), splitList(query.Get("myipv6"))...)
+
+	if len(values) == 0 {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid remote address %s: %w", r.RemoteAddr, err)
+		}
+		values = []string{host}
+	}
+
+	var ips []net.IP
+	seenIPv4, seenIPv6 := false, false
+	for _, value := range values {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address: %s", value)
+		}
+
+		if ip.To4() != nil {
+			if seenIPv4 {
+				return nil, fmt.Errorf("more than one IPv4 address given")
+			}
+			seenIPv4 = true
+		} else {
+			if seenIPv6 {
+				return nil, fmt.Errorf("more than one IPv6 address given")
+			}
+			seenIPv6 = true
+		}
+
+		ips = append(ips, ip)
+	}
+
+	return ips, nil
+}
+
+// hostnameAllowed reports whether hostname matches an entry of the allowlist.
+// Entries starting with "*." match any subdomain of the rest of the entry.
+func hostnameAllowed(hostname string, allowed []string) bool {
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(hostname, "."+suffix) {
+				return true
+			}
+			continue
+		}
+
+		if hostname == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+func splitList(value string) []string {
+	var result []string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
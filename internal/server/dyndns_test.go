@@ -0,0 +1,149 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yy4382/dns-set/internal/dns"
+)
+
+func TestDynDNSUpdate(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		username     string
+		password     string
+		expectedCode int
+		expectedBody string
+		expected     []fakeUpdate
+	}{
+		{
+			name:         "ipv4 update",
+			query:        "hostname=home.example.com&myip=203.0.113.7",
+			username:     "router",
+			password:     "secret",
+			expectedCode: http.StatusOK,
+			expectedBody: "good 203.0.113.7\n",
+			expected:     []fakeUpdate{{Domain: "home.example.com", RecordType: dns.RecordTypeA, IP: "203.0.113.7"}},
+		},
+		{
+			name:         "both families",
+			query:        "hostname=nas.lab.example.com&myip=203.0.113.7&myipv6=2001:db8::1",
+			username:     "router",
+			password:     "secret",
+			expectedCode: http.StatusOK,
+			expectedBody: "good 203.0.113.7,2001:db8::1\n",
+			expected: []fakeUpdate{
+				{Domain: "nas.lab.example.com", RecordType: dns.RecordTypeA, IP: "203.0.113.7"},
+				{Domain: "nas.lab.example.com", RecordType: dns.RecordTypeAAAA, IP: "2001:db8::1"},
+			},
+		},
+		{
+			name:         "remote address fallback",
+			query:        "hostname=home.example.com",
+			username:     "router",
+			password:     "secret",
+			expectedCode: http.StatusOK,
+			expectedBody: "good 192.0.2.1\n",
+			expected:     []fakeUpdate{{Domain: "home.example.com", RecordType: dns.RecordTypeA, IP: "192.0.2.1"}},
+		},
+		{
+			name:         "hostname not in allowlist",
+			query:        "hostname=home.example.com,other.example.com&myip=203.0.113.7",
+			username:     "router",
+			password:     "secret",
+			expectedCode: http.StatusOK,
+			expectedBody: "good 203.0.113.7\nnohost\n",
+			expected:     []fakeUpdate{{Domain: "home.example.com", RecordType: dns.RecordTypeA, IP: "203.0.113.7"}},
+		},
+		{
+			name:         "wildcard does not match apex",
+			query:        "hostname=lab.example.com&myip=203.0.113.7",
+			username:     "router",
+			password:     "secret",
+			expectedCode: http.StatusOK,
+			expectedBody: "nohost\n",
+		},
+		{
+			name:         "not fqdn",
+			query:        "hostname=home&myip=203.0.113.7",
+			username:     "router",
+			password:     "secret",
+			expectedCode: http.StatusOK,
+			expectedBody: "notfqdn\n",
+		},
+		{
+			name:         "wrong password",
+			query:        "hostname=home.example.com&myip=203.0.113.7",
+			username:     "router",
+			password:     "wrong",
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: "badauth\n",
+		},
+		{
+			name:         "invalid ip",
+			query:        "hostname=home.example.com&myip=not-an-ip",
+			username:     "router",
+			password:     "secret",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "911\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{}
+			srv := newTestServer(provider)
+
+			req := httptest.NewRequest(http.MethodGet, "/nic/update?"+tt.query, nil)
+			req.RemoteAddr = "192.0.2.1:40000"
+			req.SetBasicAuth(tt.username, tt.password)
+			rec := httptest.NewRecorder()
+
+			srv.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			assert.Equal(t, tt.expectedBody, rec.Body.String())
+			assert.Equal(t, tt.expected, provider.updates)
+		})
+	}
+}
+
+func TestDynDNSUpdate_ProviderError(t *testing.T) {
+	srv := newTestServer(&fakeProvider{err: fmt.Errorf("zone not found")})
+
+	req := httptest.NewRequest(http.MethodGet, "/nic/update?hostname=home.example.com&myip=203.0.113.7", nil)
+	req.SetBasicAuth("router", "secret")
+	rec := httptest.NewRecorder()
+
+	srv.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, "dnserr\n", rec.Body.String())
+}
+
+func TestDynDNSUpdate_NoChange(t *testing.T) {
+	srv := newTestServer(&fakeProvider{})
+
+	for _, expected := range []string{"good 203.0.113.7\n", "nochg 203.0.113.7\n"} {
+		req := httptest.NewRequest(http.MethodGet, "/nic/update?hostname=home.example.com&myip=203.0.113.7", nil)
+		req.SetBasicAuth("router", "secret")
+		rec := httptest.NewRecorder()
+
+		srv.Handler().ServeHTTP(rec, req)
+
+		assert.Equal(t, expected, rec.Body.String())
+	}
+}
+
+func TestHostnameAllowed(t *testing.T) {
+	allowed := []string{"Home.Example.com", "*.lab.example.com"}
+
+	assert.True(t, hostnameAllowed("home.example.com", allowed))
+	assert.True(t, hostnameAllowed("a.b.lab.example.com", allowed))
+	assert.False(t, hostnameAllowed("lab.example.com", allowed))
+	assert.False(t, hostnameAllowed("evillab.example.com", allowed))
+	assert.False(t, hostnameAllowed("home.example.com", nil))
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yy4382/dns-set/internal/config"
+	"github.com/yy4382/dns-set/internal/output"
+	"github.com/yy4382/dns-set/pkg/dnsset"
+)
+
+type Server struct {
+	config   *config.Config
+	provider dnsset.DNSProvider
+	printer  *output.Printer
+	mux      *http.ServeMux
+
+	logMu sync.Mutex
+
+	syncMu   sync.Mutex
+	mu       sync.Mutex
+	lastSync *dnsset.Result
+}
+
+// event is the document written for each log line in structured output.
+type event struct {
+	Time    time.Time            `json:"time" yaml:"time"`
+	Message string               `json:"message" yaml:"message"`
+	Record  *dnsset.RecordResult `json:"record,omitempty" yaml:"record,omitempty"`
+}
+
+func New(cfg *config.Config, provider dnsset.DNSProvider, printer *output.Printer) *Server {
+	s := &Server{
+		config:   cfg,
+		provider: provider,
+		printer:  printer,
+		mux:      http.NewServeMux(),
+	}
+
+	if len(cfg.Server.DynDNS.Users) > 0 {
+		s.mux.HandleFunc("GET /nic/update", s.handleDynDNSUpdate)
+	}
+	if cfg.Server.API.Token != "" {
+		s.registerAPI()
+	}
+
+	return s
+}
+
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+func (s *Server) ListenAndServe() error {
+	httpServer := &http.Server{
+		Addr:              s.config.Server.Listen,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	s.logf(nil, "Listening on %s", s.config.Server.Listen)
+	if err := httpServer.ListenAndServe(); err != nil {
+		return fmt.Errorf("server stopped: %w", err)
+	}
+
+	return nil
+}
+
+// logf writes a log line, or an event document in structured output. record
+// is attached to the event when the line is about a record update.
+func (s *Server) logf(record *dnsset.RecordResult, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+
+	if s.printer.Structured() {
+		s.printer.Print(event{Time: time.Now(), Message: message, Record: record})
+		return
+	}
+
+	fmt.Fprintln(s.printer.Writer(), message)
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"io"
+	"net"
+
+	"github.com/yy4382/dns-set/internal/config"
+	"github.com/yy4382/dns-set/internal/dns"
+	"github.com/yy4382/dns-set/internal/output"
+)
+
+type fakeUpdate struct {
+	Domain     string
+	RecordType dns.RecordType
+	IP         string
+	Proxied    bool
+}
+
+// fakeProvider keeps records in memory and logs every write.
+type fakeProvider struct {
+	records map[string]dns.Record
+	updates []fakeUpdate
+	err     error
+}
+
+func (f *fakeProvider) UpdateRecord(domain string, recordType dns.RecordType, ip net.IP, ttl *int, proxied bool) (dns.Change, error) {
+	if f.err != nil {
+		return dns.Change{}, f.err
+	}
+
+	if f.records == nil {
+		f.records = make(map[string]dns.Record)
+	}
+
+	key := domain + "/" + string(recordType)
+	record := dns.Record{ID: key, Name: domain, Type: recordType, Content: ip.String(), TTL: 1, Proxied: proxied}
+
+	old, exists := f.records[key]
+	if exists && old == record {
+		return dns.Change{Action: dns.ActionUnchanged, Old: []dns.Record{old}, New: old}, nil
+	}
+
+	f.records[key] = record
+	f.updates = append(f.updates, fakeUpdate{Domain: domain, RecordType: recordType, IP: ip.String(), Proxied: proxied})
+
+	if exists {
+		return dns.Change{Action: dns.ActionUpdated, Old: []dns.Record{old}, New: record}, nil
+	}
+	return dns.Change{Action: dns.ActionCreated, New: record}, nil
+}
+
+func (f *fakeProvider) ListRecords(domain string) ([]dns.Record, error) {
+	var records []dns.Record
+	for _, record := range f.records {
+		if record.Name == domain {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (f *fakeProvider) Name() string {
+	return "Fake"
+}
+
+func newTestServer(provider dns.DNSProvider) *Server {
+	cfg := &config.Config{
+		Sync: config.SyncConfig{
+			DomainSource: "config",
+			Domains:      []string{"app.example.com", "api.example.com"},
+			IPDetector:   "interface",
+			RecordTypes:  []string{"A"},
+		},
+		Server: config.ServerConfig{
+			DynDNS: config.DynDNSConfig{
+				Users:     []config.DynDNSUser{{Username: "router", Password: "secret"}},
+				Hostnames: []string{"home.example.com", "*.lab.example.com"},
+			},
+			API: config.APIConfig{Token: "api-token"},
+		},
+	}
+	return New(cfg, provider, output.NewPrinter(io.Discard, output.FormatText))
+}
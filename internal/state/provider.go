@@ -0,0 +1,81 @@
+package state
+
+import (
+	"log"
+	"net"
+	"time"
+
+	"github.com/yy4382/dns-set/internal/dns"
+)
+
+// Provider wraps a DNS provider and answers UpdateRecord from the state
+// file when the record was applied with the same content and settings
+// before, without calling the provider. Other calls pass through.
+type Provider struct {
+	dns.DNSProvider
+	state  *File
+	maxAge time.Duration
+
+	// Refresh makes every update go to the provider, reconciling the state.
+	Refresh bool
+
+	now func() time.Time
+}
+
+// NewProvider returns provider backed by state. Entries older than maxAge
+// are checked with the provider again; zero means they never expire.
+func NewProvider(provider dns.DNSProvider, state *File, maxAge time.Duration) *Provider {
+	return &Provider{DNSProvider: provider, state: state, maxAge: maxAge, now: time.Now}
+}
+
+func (p *Provider) UpdateRecord(domain string, recordType dns.RecordType, ip net.IP, ttl *int, proxied bool) (dns.Change, error) {
+	if entry, ok := p.state.Get(domain, recordType); ok && !p.Refresh && p.fresh(entry) &&
+		entry.Content == ip.String() && entry.Proxied == proxied && sameTTL(entry.TTL, ttl) {
+		record := dns.Record{
+			ID:      entry.RecordID,
+			Name:    domain,
+			Type:    recordType,
+			Content: entry.Content,
+			Proxied: entry.Proxied,
+			ZoneID:  entry.ZoneID,
+		}
+		if entry.TTL != nil {
+			record.TTL = *entry.TTL
+		}
+		return dns.Change{Action: dns.ActionUnchanged, Old: []dns.Record{record}, New: record}, nil
+	}
+
+	change, err := p.DNSProvider.UpdateRecord(domain, recordType, ip, ttl, proxied)
+	if err != nil {
+		// The record is in an unknown state; ask the provider next time.
+		if deleteErr := p.state.Delete(domain, recordType); deleteErr != nil {
+			log.Printf("warning: %v", deleteErr)
+		}
+		return change, err
+	}
+
+	entry := Entry{
+		Content:   change.New.Content,
+		RecordID:  change.New.ID,
+		ZoneID:    change.New.ZoneID,
+		TTL:       ttl,
+		Proxied:   change.New.Proxied,
+		UpdatedAt: p.now(),
+	}
+	if err := p.state.Set(domain, recordType, entry); err != nil {
+		log.Printf("warning: %v", err)
+	}
+
+	return change, nil
+}
+
+func (p *Provider) fresh(entry Entry) bool {
+	return p.maxAge <= 0 || p.now().Sub(entry.UpdatedAt) < p.maxAge
+}
+
+func sameTTL(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
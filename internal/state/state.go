@@ -0,0 +1,122 @@
+// Package state remembers the records dns-set applied, so that runs whose
+// addresses did not change need no calls to the DNS provider.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/yy4382/dns-set/internal/dns"
+)
+
+// Entry is the last record applied for a name and type.
+type Entry struct {
+	Content  string `json:"content"`
+	RecordID string `json:"record_id,omitempty"`
+	ZoneID   string `json:"zone_id,omitempty"`
+	// TTL is the TTL that was asked for; nil means the provider default.
+	TTL       *int      `json:"ttl,omitempty"`
+	Proxied   bool      `json:"proxied"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// File is the state file, keyed by "name/type".
+type File struct {
+	path string
+
+	mu      sync.Mutex
+	Records map[string]Entry `json:"records"`
+}
+
+// Load reads the state file at path. A missing file is an empty state.
+func Load(path string) (*File, error) {
+	file := &File{path: path, Records: make(map[string]Entry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if file.Records == nil {
+		file.Records = make(map[string]Entry)
+	}
+
+	return file, nil
+}
+
+func key(name string, recordType dns.RecordType) string {
+	return name + "/" + string(recordType)
+}
+
+// Get returns the entry of a record.
+func (f *File) Get(name string, recordType dns.RecordType) (Entry, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, ok := f.Records[key(name, recordType)]
+	return entry, ok
+}
+
+// Set stores the entry of a record and writes the file.
+func (f *File) Set(name string, recordType dns.RecordType, entry Entry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Records[key(name, recordType)] = entry
+	return f.save()
+}
+
+// Delete forgets a record and writes the file.
+func (f *File) Delete(name string, recordType dns.RecordType) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.Records[key(name, recordType)]; !ok {
+		return nil
+	}
+	delete(f.Records, key(name, recordType))
+	return f.save()
+}
+
+// save writes the file atomically, so an interrupted run cannot leave a
+// truncated state behind.
+func (f *File) save() error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(f.path), ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(append(data, '\n')); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	if err := os.Rename(temp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package state
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yy4382/dns-set/internal/dns"
+)
+
+type countingProvider struct {
+	updates int
+	err     error
+}
+
+func (p *countingProvider) UpdateRecord(domain string, recordType dns.RecordType, ip net.IP, ttl *int, proxied bool) (dns.Change, error) {
+	p.updates++
+	if p.err != nil {
+		return dns.Change{}, p.err
+	}
+	record := dns.Record{ID: "rec-1", Name: domain, Type: recordType, Content: ip.String(), Proxied: proxied, ZoneID: "zone-1"}
+	return dns.Change{Action: dns.ActionUpdated, New: record}, nil
+}
+
+func (p *countingProvider) ListRecords(domain string) ([]dns.Record, error) {
+	return nil, nil
+}
+
+func (p *countingProvider) Name() string {
+	return "Counting"
+}
+
+func TestLoad_Missing(t *testing.T) {
+	file, err := Load(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+	assert.Empty(t, file.Records)
+}
+
+func TestFile_SetAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+	file, err := Load(path)
+	require.NoError(t, err)
+
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, file.Set("home.example.com", dns.RecordTypeA, Entry{Content: "203.0.113.7", RecordID: "rec-1", ZoneID: "zone-1", UpdatedAt: updated}))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	entry, ok := loaded.Get("home.example.com", dns.RecordTypeA)
+	require.True(t, ok)
+	assert.Equal(t, Entry{Content: "203.0.113.7", RecordID: "rec-1", ZoneID: "zone-1", UpdatedAt: updated}, entry)
+
+	_, ok = loaded.Get("home.example.com", dns.RecordTypeAAAA)
+	assert.False(t, ok)
+
+	require.NoError(t, loaded.Delete("home.example.com", dns.RecordTypeA))
+	loaded, err = Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, loaded.Records)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0600))
+
+	_, err := Load(path)
+	assert.ErrorContains(t, err, "failed to parse state file")
+}
+
+func TestProvider(t *testing.T) {
+	file, err := Load(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+
+	inner := &countingProvider{}
+	provider := NewProvider(inner, file, time.Hour)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	provider.now = func() time.Time { return now }
+	ttl := 300
+
+	ip := net.ParseIP("203.0.113.7")
+	change, err := provider.UpdateRecord("home.example.com", dns.RecordTypeA, ip, &ttl, false)
+	require.NoError(t, err)
+	assert.Equal(t, dns.ActionUpdated, change.Action)
+	assert.Equal(t, 1, inner.updates)
+
+	// Unchanged: answered from the state.
+	change, err = provider.UpdateRecord("home.example.com", dns.RecordTypeA, ip, &ttl, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.updates)
+	assert.Equal(t, dns.ActionUnchanged, change.Action)
+	assert.Equal(t, dns.Record{ID: "rec-1", Name: "home.example.com", Type: dns.RecordTypeA, Content: "203.0.113.7", TTL: 300, ZoneID: "zone-1"}, change.New)
+
+	// Different settings, a new address, refresh and an expired entry all go
+	// to the provider.
+	_, err = provider.UpdateRecord("home.example.com", dns.RecordTypeA, ip, nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.updates)
+
+	_, err = provider.UpdateRecord("home.example.com", dns.RecordTypeA, net.ParseIP("203.0.113.8"), nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, inner.updates)
+
+	provider.Refresh = true
+	_, err = provider.UpdateRecord("home.example.com", dns.RecordTypeA, net.ParseIP("203.0.113.8"), nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, inner.updates)
+
+	provider.Refresh = false
+	now = now.Add(2 * time.Hour)
+	_, err = provider.UpdateRecord("home.example.com", dns.RecordTypeA, net.ParseIP("203.0.113.8"), nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, 5, inner.updates)
+
+	_, err = provider.UpdateRecord("home.example.com", dns.RecordTypeA, net.ParseIP("203.0.113.8"), nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, 5, inner.updates)
+}
+
+func TestProvider_ErrorForgetsRecord(t *testing.T) {
+	file, err := Load(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+	require.NoError(t, file.Set("home.example.com", dns.RecordTypeA, Entry{Content: "203.0.113.7", UpdatedAt: time.Now()}))
+
+	inner := &countingProvider{err: errors.New("rate limited")}
+	provider := NewProvider(inner, file, 0)
+
+	_, err = provider.UpdateRecord("home.example.com", dns.RecordTypeA, net.ParseIP("203.0.113.8"), nil, false)
+	assert.EqualError(t, err, "rate limited")
+
+	_, ok := file.Get("home.example.com", dns.RecordTypeA)
+	assert.False(t, ok)
+}
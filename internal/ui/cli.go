@@ -2,37 +2,53 @@ package ui
 
 import (
 	"bufio"
+	"context"
 	"fmt"
-	"net"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 
+	"github.com/yy4382/dns-set/internal/app"
 	"github.com/yy4382/dns-set/internal/config"
-	"github.com/yy4382/dns-set/internal/dns"
 	"github.com/yy4382/dns-set/internal/domain"
 	"github.com/yy4382/dns-set/internal/ip"
+	"github.com/yy4382/dns-set/internal/output"
+	"github.com/yy4382/dns-set/pkg/dnsset"
 	"golang.org/x/term"
 )
 
 type CLI struct {
 	config   *config.Config
-	provider dns.DNSProvider
+	provider dnsset.DNSProvider
 	scanner  *bufio.Scanner
+	printer  *output.Printer
+	out      io.Writer
 }
 
-func NewCLI(cfg *config.Config, provider dns.DNSProvider) *CLI {
+// NewCLI returns an interactive CLI. With a structured printer, prompts and
+// progress go to stderr so that stdout only carries the result document.
+func NewCLI(cfg *config.Config, provider dnsset.DNSProvider, printer *output.Printer) *CLI {
+	out := io.Writer(os.Stdout)
+	if printer.Structured() {
+		out = os.Stderr
+	}
+
 	return &CLI{
 		config:   cfg,
 		provider: provider,
 		scanner:  bufio.NewScanner(os.Stdin),
+		printer:  printer,
+		out:      out,
 	}
 }
 
 func (c *CLI) Run() error {
-	fmt.Printf("=== DNS Setter - %s Provider ===\n\n", c.provider.Name())
+	fmt.Fprintf(c.out, "=== DNS Setter - %s Provider ===\n\n", c.provider.Name())
 
 	domainSource, err := c.selectDomainSource()
 	if err != nil {
@@ -64,47 +80,40 @@ func (c *CLI) Run() error {
 		return fmt.Errorf("failed to select proxy status: %w", err)
 	}
 
-	for _, recordType := range recordTypes {
-		var ip net.IP
-		var err error
+	result, err := dnsset.Sync(context.Background(), dnsset.Spec{
+		Domains:     selectedDomains,
+		RecordTypes: recordTypes,
+		Detector:    ipDetector,
+		Provider:    c.provider,
+		TTL:         c.config.Preferences.DefaultTTL,
+		Proxied:     proxied,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sync DNS records: %w", err)
+	}
 
-		if recordType == dns.RecordTypeA {
-			ip, err = ipDetector.GetIPv4()
-		} else {
-			ip, err = ipDetector.GetIPv6()
+	if c.printer.Structured() {
+		if err := c.printer.Print(result); err != nil {
+			return err
 		}
+	} else {
+		PrintResult(c.out, result)
+	}
 
-		if err != nil {
-			fmt.Printf("Failed to get %s address: %v\n", recordType, err)
-			continue
-		}
-
-		fmt.Printf("\nDetected %s address: %s\n", recordType, ip.String())
-
-		for _, domain := range selectedDomains {
-			fmt.Printf("Updating %s record for %s...\n", recordType, domain)
-
-			err = c.provider.UpdateRecord(domain, recordType, ip, c.config.Preferences.DefaultTTL, proxied)
-			if err != nil {
-				fmt.Printf("Failed to update %s record for %s: %v\n", recordType, domain, err)
-			} else {
-				proxyStatus := "DNS only"
-				if proxied {
-					proxyStatus = "Proxied"
-				}
-				fmt.Printf("Successfully updated %s record for %s (%s)\n", recordType, domain, proxyStatus)
-			}
-		}
+	if failed := result.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d records failed to update: %w", failed, len(result.Records), output.ErrReported)
 	}
 
-	fmt.Println("\nDNS update completed!")
+	if !c.printer.Structured() {
+		fmt.Fprintln(c.out, "\nDNS update completed!")
+	}
 	return nil
 }
 
 func (c *CLI) selectDomainSource() (domain.DomainSource, error) {
-	fmt.Println("Select domain source:")
-	fmt.Println("1. Manual input")
-	fmt.Println("2. Caddyfile")
+	fmt.Fprintln(c.out, "Select domain source:")
+	fmt.Fprintln(c.out, "1. Manual input")
+	fmt.Fprintln(c.out, "2. Caddyfile")
 
 	choice, err := c.promptChoice("Enter choice (1-2): ", 1, 2)
 	if err != nil {
@@ -113,7 +122,7 @@ func (c *CLI) selectDomainSource() (domain.DomainSource, error) {
 
 	switch choice {
 	case 1:
-		return domain.NewManualSource(), nil
+		return domain.NewManualSource(c.out), nil
 	case 2:
 		caddyfilePath, err := c.promptCaddyfilePath(c.config.Preferences.CaddyfilePath)
 		if err != nil {
@@ -127,16 +136,16 @@ func (c *CLI) selectDomainSource() (domain.DomainSource, error) {
 
 func (c *CLI) selectDomains(domains []string) ([]string, error) {
 	if len(domains) == 1 {
-		fmt.Printf("Found domain: %s\n", domains[0])
+		fmt.Fprintf(c.out, "Found domain: %s\n", domains[0])
 		return domains, nil
 	}
 
-	fmt.Printf("\nFound %d domains:\n", len(domains))
+	fmt.Fprintf(c.out, "\nFound %d domains:\n", len(domains))
 	for i, domain := range domains {
-		fmt.Printf("%d. %s\n", i+1, domain)
+		fmt.Fprintf(c.out, "%d. %s\n", i+1, domain)
 	}
 
-	fmt.Println("Select domains to update (comma-separated numbers, or 'all'):")
+	fmt.Fprintln(c.out, "Select domains to update (comma-separated numbers, or 'all'):")
 	c.scanner.Scan()
 	input := strings.TrimSpace(c.scanner.Text())
 
@@ -164,33 +173,36 @@ func (c *CLI) selectDomains(domains []string) ([]string, error) {
 }
 
 func (c *CLI) selectIPDetector() (ip.IPDetector, error) {
-	fmt.Println("\nSelect IP detection method:")
-	fmt.Println("1. Network interface")
-	fmt.Println("2. External API (ip.sb)")
-	fmt.Println("3. Manual input")
+	fmt.Fprintln(c.out, "\nSelect IP detection method:")
+	fmt.Fprintln(c.out, "1. Network interface")
+	fmt.Fprintln(c.out, "2. External API")
+	fmt.Fprintln(c.out, "3. Fallback chain (ip.chain in config)")
+	fmt.Fprintln(c.out, "4. Manual input")
 
-	choice, err := c.promptChoice("Enter choice (1-3): ", 1, 3)
+	choice, err := c.promptChoice("Enter choice (1-4): ", 1, 4)
 	if err != nil {
 		return nil, err
 	}
 
 	switch choice {
 	case 1:
-		return ip.NewInterfaceDetector(), nil
+		return app.NewDetector(c.config, "interface")
 	case 2:
-		return ip.NewAPIDetector(), nil
+		return app.NewDetector(c.config, "api")
 	case 3:
-		return ip.NewManualDetector(), nil
+		return app.NewDetector(c.config, "chain")
+	case 4:
+		return ip.NewManualDetector(c.out), nil
 	default:
 		return nil, fmt.Errorf("invalid choice")
 	}
 }
 
-func (c *CLI) selectRecordTypes() ([]dns.RecordType, error) {
-	fmt.Println("\nSelect record types to update:")
-	fmt.Println("1. IPv4 (A) only")
-	fmt.Println("2. IPv6 (AAAA) only")
-	fmt.Println("3. Both IPv4 and IPv6")
+func (c *CLI) selectRecordTypes() ([]dnsset.RecordType, error) {
+	fmt.Fprintln(c.out, "\nSelect record types to update:")
+	fmt.Fprintln(c.out, "1. IPv4 (A) only")
+	fmt.Fprintln(c.out, "2. IPv6 (AAAA) only")
+	fmt.Fprintln(c.out, "3. Both IPv4 and IPv6")
 
 	choice, err := c.promptChoice("Enter choice (1-3): ", 1, 3)
 	if err != nil {
@@ -199,20 +211,20 @@ func (c *CLI) selectRecordTypes() ([]dns.RecordType, error) {
 
 	switch choice {
 	case 1:
-		return []dns.RecordType{dns.RecordTypeA}, nil
+		return []dnsset.RecordType{dnsset.RecordTypeA}, nil
 	case 2:
-		return []dns.RecordType{dns.RecordTypeAAAA}, nil
+		return []dnsset.RecordType{dnsset.RecordTypeAAAA}, nil
 	case 3:
-		return []dns.RecordType{dns.RecordTypeA, dns.RecordTypeAAAA}, nil
+		return []dnsset.RecordType{dnsset.RecordTypeA, dnsset.RecordTypeAAAA}, nil
 	default:
 		return nil, fmt.Errorf("invalid choice")
 	}
 }
 
 func (c *CLI) selectProxyStatus() (bool, error) {
-	fmt.Println("\nSelect Cloudflare proxy status:")
-	fmt.Println("1. DNS only (grey cloud)")
-	fmt.Println("2. Proxied (yellow cloud)")
+	fmt.Fprintln(c.out, "\nSelect Cloudflare proxy status:")
+	fmt.Fprintln(c.out, "1. DNS only (grey cloud)")
+	fmt.Fprintln(c.out, "2. Proxied (yellow cloud)")
 
 	choice, err := c.promptChoice("Enter choice (1-2): ", 1, 2)
 	if err != nil {
@@ -224,7 +236,7 @@ func (c *CLI) selectProxyStatus() (bool, error) {
 
 func (c *CLI) promptChoice(prompt string, min, max int) (int, error) {
 	for {
-		fmt.Print(prompt)
+		fmt.Fprint(c.out, prompt)
 		if !c.scanner.Scan() {
 			return 0, fmt.Errorf("failed to read input")
 		}
@@ -232,12 +244,12 @@ func (c *CLI) promptChoice(prompt string, min, max int) (int, error) {
 		input := strings.TrimSpace(c.scanner.Text())
 		choice, err := strconv.Atoi(input)
 		if err != nil {
-			fmt.Printf("Please enter a number between %d and %d\n", min, max)
+			fmt.Fprintf(c.out, "Please enter a number between %d and %d\n", min, max)
 			continue
 		}
 
 		if choice < min || choice > max {
-			fmt.Printf("Please enter a number between %d and %d\n", min, max)
+			fmt.Fprintf(c.out, "Please enter a number between %d and %d\n", min, max)
 			continue
 		}
 
@@ -250,8 +262,8 @@ func (c *CLI) promptCaddyfilePath(defaultPath string) (string, error) {
 		return defaultPath, nil
 	}
 
-	fmt.Printf("Caddyfile not found at %s\n", defaultPath)
-	fmt.Print("Please enter Caddyfile path (absolute or relative to current directory): ")
+	fmt.Fprintf(c.out, "Caddyfile not found at %s\n", defaultPath)
+	fmt.Fprint(c.out, "Please enter Caddyfile path (absolute or relative to current directory): ")
 
 	if !c.scanner.Scan() {
 		return "", fmt.Errorf("failed to read input")
@@ -277,24 +289,24 @@ func (c *CLI) promptCaddyfilePath(defaultPath string) (string, error) {
 		return "", fmt.Errorf("Caddyfile not found at %s: %w", resolvedPath, err)
 	}
 
-	fmt.Printf("Using Caddyfile: %s\n", resolvedPath)
+	fmt.Fprintf(c.out, "Using Caddyfile: %s\n", resolvedPath)
 	return resolvedPath, nil
 }
 
 func (c *CLI) PromptAndSaveAPIToken(configPath string) (string, error) {
-	fmt.Println("\n=== Cloudflare API Token Required ===")
-	fmt.Println("To use dns-set with Cloudflare, you need to provide an API token.")
-	fmt.Println("You can create one at: https://dash.cloudflare.com/profile/api-tokens")
-	fmt.Println("Make sure the token has the following permissions:")
-	fmt.Println("  - Zone.DNS")
-	fmt.Println("  - Resources that you want to update")
-	fmt.Print("\nPlease enter your Cloudflare API token (input will be hidden): ")
+	fmt.Fprintln(c.out, "\n=== Cloudflare API Token Required ===")
+	fmt.Fprintln(c.out, "To use dns-set with Cloudflare, you need to provide an API token.")
+	fmt.Fprintln(c.out, "You can create one at: https://dash.cloudflare.com/profile/api-tokens")
+	fmt.Fprintln(c.out, "Make sure the token has the following permissions:")
+	fmt.Fprintln(c.out, "  - Zone.DNS")
+	fmt.Fprintln(c.out, "  - Resources that you want to update")
+	fmt.Fprint(c.out, "\nPlease enter your Cloudflare API token (input will be hidden): ")
 
 	tokenBytes, err := term.ReadPassword(int(syscall.Stdin))
 	if err != nil {
 		return "", fmt.Errorf("failed to read API token: %w", err)
 	}
-	fmt.Println()
+	fmt.Fprintln(c.out)
 
 	token := strings.TrimSpace(string(tokenBytes))
 	if token == "" {
@@ -302,8 +314,8 @@ func (c *CLI) PromptAndSaveAPIToken(configPath string) (string, error) {
 	}
 
 	if len(token) < 40 {
-		fmt.Println("Warning: The entered token seems too short. Cloudflare API tokens are typically 40+ characters.")
-		fmt.Print("Do you want to continue anyway? (y/N): ")
+		fmt.Fprintln(c.out, "Warning: The entered token seems too short. Cloudflare API tokens are typically 40+ characters.")
+		fmt.Fprint(c.out, "Do you want to continue anyway? (y/N): ")
 
 		if !c.scanner.Scan() {
 			return "", fmt.Errorf("failed to read confirmation")
@@ -326,6 +338,109 @@ func (c *CLI) PromptAndSaveAPIToken(configPath string) (string, error) {
 		return "", fmt.Errorf("failed to save configuration: %w", err)
 	}
 
-	fmt.Printf("✓ API token saved to configuration file\n")
+	fmt.Fprintf(c.out, "✓ API token saved to configuration file\n")
 	return token, nil
 }
+
+// PrintResult writes a human-readable report of a sync to w.
+func PrintResult(w io.Writer, result *dnsset.Result) {
+	// Hosts with their own detector add more addresses of a type; the
+	// records of a type follow all its addresses.
+	var recordTypes []dnsset.RecordType
+	detected := make(map[dnsset.RecordType]bool)
+	for _, address := range result.Addresses {
+		if !slices.Contains(recordTypes, address.Type) {
+			recordTypes = append(recordTypes, address.Type)
+		}
+		if address.Error == "" {
+			detected[address.Type] = true
+		}
+	}
+
+	for _, recordType := range recordTypes {
+		for _, address := range result.Addresses {
+			if address.Type != recordType {
+				continue
+			}
+
+			if address.Error != "" {
+				fmt.Fprintf(w, "Failed to get %s address: %s\n", address.Type, address.Error)
+				continue
+			}
+
+			fmt.Fprintf(w, "\nDetected %s address: %s\n", address.Type, address.IP)
+		}
+
+		if !detected[recordType] {
+			continue
+		}
+
+		for _, record := range result.Records {
+			if record.Type != recordType {
+				continue
+			}
+
+			if record.Action == dnsset.ActionFailed {
+				fmt.Fprintf(w, "Failed to update %s record for %s: %s\n", record.Type, record.Domain, record.Error)
+				continue
+			}
+
+			proxyStatus := "DNS only"
+			if record.New.Proxied {
+				proxyStatus = "Proxied"
+			}
+
+			switch record.Action {
+			case dnsset.ActionCreated:
+				fmt.Fprintf(w, "Successfully created %s record for %s (%s)\n", record.Type, record.Domain, proxyStatus)
+			case dnsset.ActionUpdated:
+				fmt.Fprintf(w, "Successfully updated %s record for %s (%s)\n", record.Type, record.Domain, proxyStatus)
+			default:
+				fmt.Fprintf(w, "%s record for %s is already up to date (%s)\n", record.Type, record.Domain, proxyStatus)
+			}
+		}
+	}
+}
+
+// PrintRecords writes the records of a listing as a table to w.
+func PrintRecords(w io.Writer, result *dnsset.ListResult) {
+	for _, address := range result.Addresses {
+		if address.Error != "" {
+			fmt.Fprintf(w, "Failed to get %s address: %s\n", address.Type, address.Error)
+			continue
+		}
+		fmt.Fprintf(w, "This host's %s address: %s (%s)\n", address.Type, address.IP, address.Source)
+	}
+	if len(result.Addresses) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tTYPE\tCONTENT\tTTL\tPROXIED\tMATCHES HOST")
+	for _, record := range result.Records {
+		ttl := strconv.Itoa(record.TTL)
+		if record.TTL == 1 {
+			ttl = "auto"
+		}
+
+		matches := "-"
+		if record.MatchesHost != nil {
+			matches = "no"
+			if *record.MatchesHost {
+				matches = "yes"
+			}
+		}
+
+		proxied := "no"
+		if record.Proxied {
+			proxied = "yes"
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", record.Name, record.Type, record.Content, ttl, proxied, matches)
+	}
+	table.Flush()
+
+	for _, listError := range result.Errors {
+		fmt.Fprintf(w, "Failed to list records for %s: %s\n", listError.Name, listError.Error)
+	}
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yy4382/dns-set/pkg/dnsset"
+)
+
+func TestPrintResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   *dnsset.Result
+		expected string
+	}{
+		{
+			name: "two A addresses from two detectors",
+			result: &dnsset.Result{
+				Addresses: []dnsset.Address{
+					{Type: dnsset.RecordTypeA, IP: "203.0.113.10", Source: "External API"},
+					{Type: dnsset.RecordTypeA, IP: "192.0.2.20", Source: "Network Interface (eth1)"},
+				},
+				Records: []dnsset.RecordResult{
+					{Domain: "a.example.com", Type: dnsset.RecordTypeA, Action: dnsset.ActionCreated, New: &dnsset.Record{Proxied: true}},
+					{Domain: "b.example.com", Type: dnsset.RecordTypeA, Action: dnsset.ActionUnchanged, New: &dnsset.Record{}},
+				},
+			},
+			expected: "\nDetected A address: 203.0.113.10\n" +
+				"\nDetected A address: 192.0.2.20\n" +
+				"Successfully created A record for a.example.com (Proxied)\n" +
+				"A record for b.example.com is already up to date (DNS only)\n",
+		},
+		{
+			name: "records follow the addresses of their type",
+			result: &dnsset.Result{
+				Addresses: []dnsset.Address{
+					{Type: dnsset.RecordTypeA, IP: "203.0.113.10", Source: "External API"},
+					{Type: dnsset.RecordTypeAAAA, IP: "2001:db8::10", Source: "External API"},
+				},
+				Records: []dnsset.RecordResult{
+					{Domain: "a.example.com", Type: dnsset.RecordTypeA, Action: dnsset.ActionUpdated, New: &dnsset.Record{}},
+					{Domain: "a.example.com", Type: dnsset.RecordTypeAAAA, Action: dnsset.ActionFailed, Error: "rate limited"},
+				},
+			},
+			expected: "\nDetected A address: 203.0.113.10\n" +
+				"Successfully updated A record for a.example.com (DNS only)\n" +
+				"\nDetected AAAA address: 2001:db8::10\n" +
+				"Failed to update AAAA record for a.example.com: rate limited\n",
+		},
+		{
+			name: "records are skipped when detection failed",
+			result: &dnsset.Result{
+				Addresses: []dnsset.Address{
+					{Type: dnsset.RecordTypeAAAA, Source: "External API", Error: "no IPv6 connectivity"},
+				},
+			},
+			expected: "Failed to get AAAA address: no IPv6 connectivity\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			PrintResult(&buf, tt.result)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package watch
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Multicast groups of address changes, from linux/rtnetlink.h.
+const (
+	rtmgrpIPv4IfAddr = 0x10
+	rtmgrpIPv6IfAddr = 0x100
+)
+
+// subscribeAddressEvents listens for RTM_NEWADDR and RTM_DELADDR messages
+// on an rtnetlink socket.
+func subscribeAddressEvents(ctx context.Context) (<-chan AddressEvent, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netlink socket: %w", err)
+	}
+
+	address := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: rtmgrpIPv4IfAddr | rtmgrpIPv6IfAddr,
+	}
+	if err := syscall.Bind(fd, address); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to subscribe to address events: %w", err)
+	}
+
+	// Non-blocking, the runtime poller lets Close interrupt a pending read.
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	file := os.NewFile(uintptr(fd), "netlink")
+
+	events := make(chan AddressEvent)
+	go func() {
+		<-ctx.Done()
+		file.Close()
+	}()
+	go readAddressEvents(ctx, file, events)
+
+	return events, nil
+}
+
+// readAddressEvents sends the address events read from the netlink socket
+// r until r is closed, fails or ctx is done, then closes events. When the
+// receive buffer overflowed, the dropped messages are reported as one
+// Overflow event.
+func readAddressEvents(ctx context.Context, r io.Reader, events chan<- AddressEvent) {
+	defer close(events)
+
+	buf := make([]byte, os.Getpagesize()*4)
+	for {
+		n, err := r.Read(buf)
+		if ctx.Err() != nil || errors.Is(err, os.ErrClosed) || errors.Is(err, io.EOF) {
+			return
+		}
+
+		var received []AddressEvent
+		switch {
+		case errors.Is(err, syscall.ENOBUFS):
+			received = []AddressEvent{{Overflow: true}}
+		case errors.Is(err, syscall.EINTR) || errors.Is(err, syscall.EAGAIN):
+			continue
+		case err != nil:
+			log.Printf("reading address events failed: %v", err)
+			return
+		default:
+			received = parseAddressMessages(buf[:n])
+		}
+
+		for _, event := range received {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// parseAddressMessages returns the address events in a netlink datagram.
+func parseAddressMessages(data []byte) []AddressEvent {
+	messages, err := syscall.ParseNetlinkMessage(data)
+	if err != nil {
+		return nil
+	}
+
+	var events []AddressEvent
+	for _, message := range messages {
+		if message.Header.Type != syscall.RTM_NEWADDR && message.Header.Type != syscall.RTM_DELADDR {
+			continue
+		}
+		if len(message.Data) < syscall.SizeofIfAddrmsg {
+			continue
+		}
+
+		attributes, err := syscall.ParseNetlinkRouteAttr(&message)
+		if err != nil {
+			continue
+		}
+
+		// IFA_LOCAL is the own address on point-to-point links, where
+		// IFA_ADDRESS is the peer.
+		var addr net.IP
+		for _, attribute := range attributes {
+			switch attribute.Attr.Type {
+			case syscall.IFA_LOCAL:
+				addr = net.IP(attribute.Value)
+			case syscall.IFA_ADDRESS:
+				if addr == nil {
+					addr = net.IP(attribute.Value)
+				}
+			}
+		}
+		if addr == nil {
+			continue
+		}
+
+		event := AddressEvent{
+			IP:      append(net.IP{}, addr...),
+			Removed: message.Header.Type == syscall.RTM_DELADDR,
+		}
+
+		index := binary.NativeEndian.Uint32(message.Data[4:8])
+		if iface, err := net.InterfaceByIndex(int(index)); err == nil {
+			event.Interface = iface.Name
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
@@ -0,0 +1,109 @@
+package watch
+
+import (
+	"context"
+	"encoding/binary"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addressMessage builds an rtnetlink address message with the given
+// attributes.
+func addressMessage(messageType uint16, family uint8, index uint32, attributes map[uint16]net.IP) []byte {
+	body := make([]byte, syscall.SizeofIfAddrmsg)
+	body[0] = family
+	binary.NativeEndian.PutUint32(body[4:8], index)
+
+	for attributeType, value := range attributes {
+		attribute := make([]byte, syscall.SizeofRtAttr+len(value))
+		binary.NativeEndian.PutUint16(attribute[0:2], uint16(len(attribute)))
+		binary.NativeEndian.PutUint16(attribute[2:4], attributeType)
+		copy(attribute[syscall.SizeofRtAttr:], value)
+		for len(attribute)%4 != 0 {
+			attribute = append(attribute, 0)
+		}
+		body = append(body, attribute...)
+	}
+
+	message := make([]byte, syscall.NLMSG_HDRLEN, syscall.NLMSG_HDRLEN+len(body))
+	binary.NativeEndian.PutUint32(message[0:4], uint32(syscall.NLMSG_HDRLEN+len(body)))
+	binary.NativeEndian.PutUint16(message[4:6], messageType)
+	return append(message, body...)
+}
+
+func TestParseAddressMessages(t *testing.T) {
+	loopback, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("no loopback interface")
+	}
+
+	var data []byte
+	data = append(data, addressMessage(syscall.RTM_NEWADDR, syscall.AF_INET, uint32(loopback.Index), map[uint16]net.IP{
+		syscall.IFA_ADDRESS: net.ParseIP("93.184.216.1").To4(),
+		syscall.IFA_LOCAL:   net.ParseIP("93.184.216.34").To4(),
+	})...)
+	data = append(data, addressMessage(syscall.RTM_DELADDR, syscall.AF_INET6, uint32(loopback.Index), map[uint16]net.IP{
+		syscall.IFA_ADDRESS: net.ParseIP("2a01:4f8::1"),
+	})...)
+	data = append(data, addressMessage(syscall.RTM_NEWLINK, syscall.AF_INET, uint32(loopback.Index), nil)...)
+
+	events := parseAddressMessages(data)
+	require.Len(t, events, 2)
+	assert.Equal(t, AddressEvent{Interface: "lo", IP: net.ParseIP("93.184.216.34").To4()}, events[0])
+	assert.Equal(t, AddressEvent{Interface: "lo", IP: net.ParseIP("2a01:4f8::1"), Removed: true}, events[1])
+}
+
+// scriptedReader returns one read result per call, like an rtnetlink socket
+// delivering datagrams.
+type scriptedReader struct {
+	reads []scriptedRead
+}
+
+type scriptedRead struct {
+	data []byte
+	err  error
+}
+
+func (r *scriptedReader) Read(buf []byte) (int, error) {
+	if len(r.reads) == 0 {
+		return 0, os.ErrClosed
+	}
+	read := r.reads[0]
+	r.reads = r.reads[1:]
+	return copy(buf, read.data), read.err
+}
+
+func TestReadAddressEvents(t *testing.T) {
+	message := addressMessage(syscall.RTM_NEWADDR, syscall.AF_INET6, 0, map[uint16]net.IP{
+		syscall.IFA_ADDRESS: net.ParseIP("2a01:4f8::1"),
+	})
+	reader := &scriptedReader{reads: []scriptedRead{
+		{data: message},
+		{err: &fs.PathError{Op: "read", Path: "netlink", Err: syscall.ENOBUFS}},
+		{err: &fs.PathError{Op: "read", Path: "netlink", Err: syscall.EINTR}},
+		{data: message},
+		{err: &fs.PathError{Op: "read", Path: "netlink", Err: syscall.EBADF}},
+		{data: message},
+	}}
+
+	events := make(chan AddressEvent)
+	go readAddressEvents(context.Background(), reader, events)
+
+	var received []AddressEvent
+	for event := range events {
+		received = append(received, event)
+	}
+
+	// An overflow does not end the events; any other read error does.
+	assert.Equal(t, []AddressEvent{
+		{IP: net.ParseIP("2a01:4f8::1")},
+		{Overflow: true},
+		{IP: net.ParseIP("2a01:4f8::1")},
+	}, received)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package watch
+
+import "context"
+
+func subscribeAddressEvents(ctx context.Context) (<-chan AddressEvent, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,100 @@
+// Package watch runs a task whenever the addresses of this host may have
+// changed: on address events from the kernel where available, and on a
+// fixed interval as a fallback.
+package watch
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"time"
+)
+
+const (
+	DefaultInterval = 5 * time.Minute
+	DefaultDebounce = 5 * time.Second
+)
+
+// ErrUnsupported is returned by the event source on systems without
+// address events.
+var ErrUnsupported = errors.New("address events are not supported on this system")
+
+// AddressEvent is an address added to or removed from an interface.
+// Overflow events stand for changes the kernel dropped; they carry no
+// address and are never filtered.
+type AddressEvent struct {
+	Interface string
+	IP        net.IP
+	Removed   bool
+	Overflow  bool
+}
+
+// Watcher runs a task once at start, after address events settled and on
+// a fixed interval.
+type Watcher struct {
+	interval time.Duration
+	debounce time.Duration
+
+	// Filter selects the events that trigger the task; nil means all.
+	Filter func(AddressEvent) bool
+
+	// subscribe returns the address events until ctx is done.
+	subscribe func(ctx context.Context) (<-chan AddressEvent, error)
+}
+
+// New returns a watcher running its task every interval and debounce after
+// the last of a burst of address events. Zero values use the defaults.
+func New(interval, debounce time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	return &Watcher{interval: interval, debounce: debounce, subscribe: subscribeAddressEvents}
+}
+
+// Run runs task until ctx is done. Without address events it only polls.
+func (w *Watcher) Run(ctx context.Context, task func(ctx context.Context, reason string)) error {
+	events, err := w.subscribe(ctx)
+	if err != nil {
+		log.Printf("watching address events failed, polling every %s: %v", w.interval, err)
+		events = nil
+	} else {
+		log.Printf("watching address events, polling every %s", w.interval)
+	}
+
+	task(ctx, "start")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	debounce := time.NewTimer(w.debounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				log.Printf("address events stopped, polling every %s", w.interval)
+				events = nil
+				continue
+			}
+			if !event.Overflow && w.Filter != nil && !w.Filter(event) {
+				continue
+			}
+			// Wait for a burst of events, like a renumbering, to settle.
+			debounce.Reset(w.debounce)
+		case <-debounce.C:
+			task(ctx, "address change")
+			ticker.Reset(w.interval)
+		case <-ticker.C:
+			task(ctx, "interval")
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package watch
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runRecorder collects the reasons the task was run for.
+type runRecorder struct {
+	mu      sync.Mutex
+	reasons []string
+}
+
+func (r *runRecorder) task(ctx context.Context, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reasons = append(r.reasons, reason)
+}
+
+func (r *runRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.reasons...)
+}
+
+func newTestWatcher(interval, debounce time.Duration, events chan AddressEvent, err error) *Watcher {
+	watcher := New(interval, debounce)
+	watcher.subscribe = func(ctx context.Context) (<-chan AddressEvent, error) {
+		if err != nil {
+			return nil, err
+		}
+		return events, nil
+	}
+	return watcher
+}
+
+func TestWatcher_DebouncesEvents(t *testing.T) {
+	events := make(chan AddressEvent)
+	watcher := newTestWatcher(time.Hour, 50*time.Millisecond, events, nil)
+	watcher.Filter = func(event AddressEvent) bool {
+		return event.Interface == "eth0"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	var recorder runRecorder
+	go func() { done <- watcher.Run(ctx, recorder.task) }()
+
+	// A renumbering produces a burst of events; it runs the task once.
+	for i := 0; i < 5; i++ {
+		events <- AddressEvent{Interface: "eth0", IP: net.ParseIP("2a01:4f8::1")}
+	}
+	assert.Eventually(t, func() bool { return len(recorder.get()) == 2 }, time.Second, 10*time.Millisecond)
+
+	// Filtered events are ignored.
+	events <- AddressEvent{Interface: "docker0", IP: net.ParseIP("93.184.216.34")}
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, []string{"start", "address change"}, recorder.get())
+
+	// Dropped events may have been relevant; they are never filtered.
+	events <- AddressEvent{Overflow: true}
+	assert.Eventually(t, func() bool { return len(recorder.get()) == 3 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, []string{"start", "address change", "address change"}, recorder.get())
+}
+
+func TestWatcher_PollsWithoutEvents(t *testing.T) {
+	watcher := newTestWatcher(30*time.Millisecond, time.Second, nil, ErrUnsupported)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	var recorder runRecorder
+	go func() { done <- watcher.Run(ctx, recorder.task) }()
+
+	assert.Eventually(t, func() bool { return len(recorder.get()) >= 3 }, time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	reasons := recorder.get()
+	assert.Equal(t, "start", reasons[0])
+	assert.Equal(t, "interval", reasons[1])
+}
+
+func TestWatcher_PollsAfterEventsStop(t *testing.T) {
+	events := make(chan AddressEvent)
+	close(events)
+	watcher := newTestWatcher(30*time.Millisecond, time.Second, events, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	var recorder runRecorder
+	go func() { done <- watcher.Run(ctx, recorder.task) }()
+
+	assert.Eventually(t, func() bool { return len(recorder.get()) >= 2 }, time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, []string{"start", "interval"}, recorder.get()[:2])
+}
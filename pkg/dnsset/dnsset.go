@@ -0,0 +1,231 @@
+// Package dnsset is the public API of dns-set. It detects the addresses of
+// this host and brings the A/AAAA records of a set of domains on a DNS
+// provider up to date. The dns-set command line tool and server are built on
+// top of it.
+//
+// A sync is described by a Spec and run with Sync:
+//
+//	provider, err := dnsset.NewCloudflareProvider(token)
+//	...
+//	result, err := dnsset.Sync(ctx, dnsset.Spec{
+//		Domains:     []string{"app.example.com"},
+//		RecordTypes: []dnsset.RecordType{dnsset.RecordTypeA},
+//		Detector:    dnsset.NewAPIDetector(),
+//		Provider:    provider,
+//	})
+//
+// Providers, detectors and domain sources are interfaces, so callers can
+// plug in their own implementations.
+//
+// Several types, such as Record and IPDetector, are aliases of types in
+// internal packages. They are part of this API all the same: their fields
+// and methods only change together with Version, like the identifiers
+// declared here.
+package dnsset
+
+import (
+	"net"
+	"time"
+
+	"github.com/yy4382/dns-set/internal/dns"
+	"github.com/yy4382/dns-set/internal/domain"
+	"github.com/yy4382/dns-set/internal/ip"
+)
+
+// Version is the version of this API. It follows semantic versioning: the
+// exported identifiers of the package only change incompatibly with a new
+// major version.
+const Version = "1.3.0"
+
+// DNSProvider creates and updates records on a DNS hosting service.
+type DNSProvider = dns.DNSProvider
+
+// ZoneLister is implemented by providers that can list a whole zone.
+type ZoneLister = dns.ZoneLister
+
+// IPDetector finds the addresses that records should point to.
+type IPDetector = ip.IPDetector
+
+// DomainSource yields the domains whose records should be updated.
+type DomainSource = domain.DomainSource
+
+type (
+	RecordType = dns.RecordType
+	Record     = dns.Record
+	Action     = dns.Action
+	Change     = dns.Change
+)
+
+const (
+	RecordTypeA    = dns.RecordTypeA
+	RecordTypeAAAA = dns.RecordTypeAAAA
+)
+
+const (
+	ActionCreated   = dns.ActionCreated
+	ActionUpdated   = dns.ActionUpdated
+	ActionUnchanged = dns.ActionUnchanged
+
+	// ActionFailed marks a record that could not be brought to the wanted
+	// state.
+	ActionFailed Action = "failed"
+)
+
+func NewCloudflareProvider(apiToken string) (DNSProvider, error) {
+	return dns.NewCloudflareProvider(apiToken)
+}
+
+// NewAPIDetector returns a detector that asks external echo services.
+func NewAPIDetector() IPDetector {
+	return ip.NewAPIDetector()
+}
+
+// NewInterfaceDetector returns a detector that reads the public addresses
+// of the network interfaces of this host.
+func NewInterfaceDetector() IPDetector {
+	return ip.NewInterfaceDetector()
+}
+
+// Endpoint is an IP echo service for NewAPIDetectorWithEndpoints.
+type (
+	Endpoint   = ip.Endpoint
+	EchoFormat = ip.EchoFormat
+)
+
+const (
+	EchoFormatText  = ip.EchoFormatText
+	EchoFormatJSON  = ip.EchoFormatJSON
+	EchoFormatRegex = ip.EchoFormatRegex
+)
+
+// NewAPIDetectorWithEndpoints returns a detector that tries the echo
+// services of a family in order. A family without endpoints uses ip.sb.
+func NewAPIDetectorWithEndpoints(ipv4, ipv6 []Endpoint) (IPDetector, error) {
+	detector, err := ip.NewAPIDetectorWithEndpoints(ipv4, ipv6)
+	if err != nil {
+		return nil, err
+	}
+	return detector, nil
+}
+
+// NewConsensusDetector returns a detector that asks the sources of a family
+// in parallel and accepts an address reported by at least quorum of them.
+// A quorum of zero or less means a majority.
+func NewConsensusDetector(quorum int, ipv4, ipv6 []IPDetector) IPDetector {
+	return ip.NewConsensusDetector(quorum, ipv4, ipv6)
+}
+
+// NewSTUNDetector returns a detector sending STUN Binding requests to
+// servers ("host:port") in order. Without servers public ones are used; a
+// zero timeout means 3 seconds per server.
+func NewSTUNDetector(servers []string, timeout time.Duration) IPDetector {
+	return ip.NewSTUNDetector(servers, timeout)
+}
+
+// DNSQuery is a "whoami" lookup for NewDNSDetector.
+type (
+	DNSQuery      = ip.DNSQuery
+	DNSAnswerType = ip.DNSAnswerType
+)
+
+const (
+	DNSAnswerAddress = ip.DNSAnswerAddress
+	DNSAnswerTXT     = ip.DNSAnswerTXT
+)
+
+// NewDNSDetector returns a detector resolving the whoami queries of a
+// family in order. A family without queries uses OpenDNS and Google; a zero
+// timeout means 3 seconds per query.
+func NewDNSDetector(ipv4, ipv6 []DNSQuery, timeout time.Duration) (IPDetector, error) {
+	detector, err := ip.NewDNSDetector(ipv4, ipv6, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return detector, nil
+}
+
+// NewInterfaceDetectorWithFilter returns an interface detector reading only
+// the interfaces matching an include pattern, or all when include is empty,
+// and none matching an exclude pattern. Patterns are names or globs.
+func NewInterfaceDetectorWithFilter(include, exclude []string) (IPDetector, error) {
+	detector, err := ip.NewInterfaceDetectorWithFilter(include, exclude)
+	if err != nil {
+		return nil, err
+	}
+	return detector, nil
+}
+
+// NewLANDetector returns an interface detector reading the private (RFC
+// 1918 and unique local) addresses of the interfaces, for internal names.
+// include and exclude select interfaces like NewInterfaceDetectorWithFilter.
+func NewLANDetector(include, exclude []string) (IPDetector, error) {
+	detector, err := ip.NewInterfaceDetectorWithFilter(include, exclude)
+	if err != nil {
+		return nil, err
+	}
+	detector.Classes = []ip.AddressClass{ip.ClassPrivate}
+	return detector, nil
+}
+
+// Cloud names a cloud provider for NewMetadataDetector.
+type Cloud = ip.Cloud
+
+const (
+	CloudAWS          = ip.CloudAWS
+	CloudGCP          = ip.CloudGCP
+	CloudHetzner      = ip.CloudHetzner
+	CloudDigitalOcean = ip.CloudDigitalOcean
+	CloudOracle       = ip.CloudOracle
+)
+
+// NewMetadataDetector returns a detector reading the public addresses of
+// a cloud instance from the metadata service of cloud, or of the cloud
+// found by probing when cloud is empty. A zero timeout means 2 seconds.
+func NewMetadataDetector(cloud Cloud, timeout time.Duration) (IPDetector, error) {
+	detector, err := ip.NewMetadataDetector(cloud, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return detector, nil
+}
+
+// NewRouterDetector returns a detector asking the router for its external
+// IPv4 address over UPnP, NAT-PMP and PCP. An empty gateway means the
+// gateway of the default route; a zero timeout means 2 seconds per step.
+func NewRouterDetector(gateway string, timeout time.Duration) IPDetector {
+	return ip.NewRouterDetector(gateway, timeout)
+}
+
+// NewCommandDetector returns a detector running a command per family, given
+// as program and arguments, and reading the address from the first line of
+// its output. A zero timeout means 10 seconds.
+func NewCommandDetector(ipv4, ipv6 []string, timeout time.Duration) (IPDetector, error) {
+	detector, err := ip.NewCommandDetector(ipv4, ipv6, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return detector, nil
+}
+
+// NewChainDetector returns a detector trying the detectors of a family in
+// order until one finds an address.
+func NewChainDetector(ipv4, ipv6 []IPDetector) IPDetector {
+	return ip.NewChainDetector(ipv4, ipv6)
+}
+
+// ParseInterfaceID parses a Host.IPv6Suffix: an IPv6 address like "::10"
+// or a MAC address, which becomes its modified EUI-64 interface ID.
+func ParseInterfaceID(value string) (net.IP, error) {
+	return ip.ParseInterfaceID(value)
+}
+
+// NewCaddyfileSource returns a source reading the site addresses of the
+// Caddyfile at path.
+func NewCaddyfileSource(path string) DomainSource {
+	return domain.NewCaddyfileSource(path)
+}
+
+// NewListSource returns a source yielding a fixed list of domains.
+func NewListSource(domains []string) DomainSource {
+	return domain.NewListSource(domains)
+}
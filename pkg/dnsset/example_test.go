@@ -0,0 +1,131 @@
+package dnsset_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/yy4382/dns-set/pkg/dnsset"
+)
+
+// fixedDetector always reports the same addresses.
+type fixedDetector struct {
+	ipv4 net.IP
+}
+
+func (f fixedDetector) GetIPv4() (net.IP, error) {
+	return f.ipv4, nil
+}
+
+func (f fixedDetector) GetIPv6() (net.IP, error) {
+	return nil, fmt.Errorf("no IPv6 connectivity")
+}
+
+func (f fixedDetector) Name() string {
+	return "Fixed"
+}
+
+// memoryProvider keeps records in a map instead of a DNS hosting service.
+type memoryProvider struct {
+	records map[string]dnsset.Record
+}
+
+func (m *memoryProvider) UpdateRecord(domain string, recordType dnsset.RecordType, ip net.IP, ttl *int, proxied bool) (dnsset.Change, error) {
+	key := domain + "/" + string(recordType)
+	record := dnsset.Record{Name: domain, Type: recordType, Content: ip.String(), TTL: 300, Proxied: proxied}
+
+	old, exists := m.records[key]
+	m.records[key] = record
+
+	switch {
+	case !exists:
+		return dnsset.Change{Action: dnsset.ActionCreated, New: record}, nil
+	case old == record:
+		return dnsset.Change{Action: dnsset.ActionUnchanged, Old: []dnsset.Record{old}, New: record}, nil
+	default:
+		return dnsset.Change{Action: dnsset.ActionUpdated, Old: []dnsset.Record{old}, New: record}, nil
+	}
+}
+
+func (m *memoryProvider) ListRecords(domain string) ([]dnsset.Record, error) {
+	var records []dnsset.Record
+	for _, record := range m.records {
+		if record.Name == domain {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (m *memoryProvider) Name() string {
+	return "Memory"
+}
+
+func ExampleSync() {
+	provider := &memoryProvider{records: map[string]dnsset.Record{
+		"www.example.com/A": {Name: "www.example.com", Type: dnsset.RecordTypeA, Content: "198.51.100.1", TTL: 300},
+	}}
+
+	result, err := dnsset.Sync(context.Background(), dnsset.Spec{
+		Domains:     []string{"app.example.com", "www.example.com"},
+		RecordTypes: []dnsset.RecordType{dnsset.RecordTypeA, dnsset.RecordTypeAAAA},
+		Detector:    fixedDetector{ipv4: net.ParseIP("203.0.113.7")},
+		Provider:    provider,
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, address := range result.Addresses {
+		if address.Error != "" {
+			fmt.Printf("%s address: %s\n", address.Type, address.Error)
+			continue
+		}
+		fmt.Printf("%s address: %s (%s)\n", address.Type, address.IP, address.Source)
+	}
+	for _, record := range result.Records {
+		if record.Action == dnsset.ActionFailed {
+			fmt.Printf("%s %s: %s\n", record.Type, record.Domain, record.Error)
+			continue
+		}
+		fmt.Printf("%s %s: %s -> %s\n", record.Type, record.Domain, record.Action, record.New.Content)
+	}
+	fmt.Printf("%d failed\n", result.Failed())
+
+	// Output:
+	// A address: 203.0.113.7 (Fixed)
+	// AAAA address: no IPv6 connectivity
+	// A app.example.com: created -> 203.0.113.7
+	// A www.example.com: updated -> 203.0.113.7
+	// AAAA app.example.com: no AAAA address: no IPv6 connectivity
+	// AAAA www.example.com: no AAAA address: no IPv6 connectivity
+	// 2 failed
+}
+
+func ExampleNewListSource() {
+	source := dnsset.NewListSource([]string{"app.example.com", "api.example.com"})
+
+	domains, err := source.GetDomains()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(source.Name(), domains)
+
+	// Output:
+	// Config [app.example.com api.example.com]
+}
+
+func ExampleParseRecordType() {
+	recordType, err := dnsset.ParseRecordType("aaaa")
+	fmt.Println(recordType, err)
+
+	_, err = dnsset.ParseRecordType("MX")
+	fmt.Println(err)
+
+	// Output:
+	// AAAA <nil>
+	// unsupported record type: MX
+}
@@ -0,0 +1,113 @@
+package dnsset
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// ListSpec describes which records List inspects: the records called one of
+// Names, or all records of Zone. The provider must implement ZoneLister to
+// list a zone. Without a detector, records are not compared with this host.
+type ListSpec struct {
+	Names    []string
+	Zone     string
+	Detector IPDetector
+	Provider DNSProvider
+}
+
+// ListedRecord is a record together with whether it points at this host.
+// MatchesHost is nil when the address of this host is unknown.
+type ListedRecord struct {
+	Record      `yaml:",inline"`
+	MatchesHost *bool `json:"matches_host,omitempty" yaml:"matches_host,omitempty"`
+}
+
+// ListError reports a name or zone whose records could not be listed.
+type ListError struct {
+	Name  string `json:"name" yaml:"name"`
+	Error string `json:"error" yaml:"error"`
+}
+
+type ListResult struct {
+	Addresses []Address      `json:"addresses,omitempty" yaml:"addresses,omitempty"`
+	Records   []ListedRecord `json:"records" yaml:"records"`
+	Errors    []ListError    `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// List fetches the current A/AAAA records described by spec and compares
+// them with the addresses of this host. Names that cannot be listed are
+// reported in the result; an error is only returned for an invalid spec or
+// when ctx is done.
+func List(ctx context.Context, spec ListSpec) (*ListResult, error) {
+	if spec.Provider == nil {
+		return nil, fmt.Errorf("no DNS provider configured")
+	}
+	if len(spec.Names) == 0 && spec.Zone == "" {
+		return nil, fmt.Errorf("no names or zone to list")
+	}
+
+	result := &ListResult{Records: []ListedRecord{}}
+
+	var records []Record
+	if spec.Zone != "" {
+		zoneLister, ok := spec.Provider.(ZoneLister)
+		if !ok {
+			return nil, fmt.Errorf("%s provider cannot list whole zones", spec.Provider.Name())
+		}
+
+		zoneRecords, err := zoneLister.ListZoneRecords(spec.Zone)
+		if err != nil {
+			result.Errors = append(result.Errors, ListError{Name: spec.Zone, Error: err.Error()})
+		}
+		records = append(records, zoneRecords...)
+	}
+
+	for _, name := range spec.Names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		nameRecords, err := spec.Provider.ListRecords(name)
+		if err != nil {
+			result.Errors = append(result.Errors, ListError{Name: name, Error: err.Error()})
+			continue
+		}
+		records = append(records, nameRecords...)
+	}
+
+	hostAddresses := make(map[RecordType]net.IP)
+	if spec.Detector != nil {
+		for _, recordType := range []RecordType{RecordTypeA, RecordTypeAAAA} {
+			if !hasRecordType(records, recordType) {
+				continue
+			}
+
+			address, detected := DetectAddress(spec.Detector, recordType)
+			result.Addresses = append(result.Addresses, address)
+			if detected != nil {
+				hostAddresses[recordType] = detected
+			}
+		}
+	}
+
+	for _, record := range records {
+		listed := ListedRecord{Record: record}
+		if hostAddress, ok := hostAddresses[record.Type]; ok {
+			matches := hostAddress.Equal(net.ParseIP(record.Content))
+			listed.MatchesHost = &matches
+		}
+		result.Records = append(result.Records, listed)
+	}
+
+	return result, nil
+}
+
+func hasRecordType(records []Record, recordType RecordType) bool {
+	for _, record := range records {
+		if record.Type == recordType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package dnsset
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type listProvider struct {
+	fakeProvider
+	records []Record
+}
+
+func (l *listProvider) ListRecords(domain string) ([]Record, error) {
+	if domain == "missing.example.com" {
+		return nil, fmt.Errorf("no zone found for domain %s", domain)
+	}
+
+	var records []Record
+	for _, record := range l.records {
+		if record.Name == domain {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (l *listProvider) ListZoneRecords(zone string) ([]Record, error) {
+	return l.records, nil
+}
+
+func TestList(t *testing.T) {
+	provider := &listProvider{records: []Record{
+		{ID: "1", Name: "app.example.com", Type: RecordTypeA, Content: "203.0.113.7", TTL: 1},
+		{ID: "2", Name: "app.example.com", Type: RecordTypeAAAA, Content: "2001:db8::1", TTL: 300, Proxied: true},
+		{ID: "3", Name: "old.example.com", Type: RecordTypeA, Content: "198.51.100.1", TTL: 1},
+	}}
+
+	result, err := List(context.Background(), ListSpec{
+		Names:    []string{"app.example.com", "old.example.com", "missing.example.com"},
+		Detector: &staticDetector{ipv4: net.ParseIP("203.0.113.7")},
+		Provider: provider,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, result.Records, 3)
+	assert.True(t, *result.Records[0].MatchesHost)
+	assert.Nil(t, result.Records[1].MatchesHost)
+	assert.False(t, *result.Records[2].MatchesHost)
+
+	assert.Len(t, result.Addresses, 2)
+	assert.Equal(t, []ListError{{Name: "missing.example.com", Error: "no zone found for domain missing.example.com"}}, result.Errors)
+}
+
+func TestList_Zone(t *testing.T) {
+	provider := &listProvider{records: []Record{
+		{ID: "1", Name: "app.example.com", Type: RecordTypeA, Content: "203.0.113.7", TTL: 1},
+		{ID: "3", Name: "old.example.com", Type: RecordTypeA, Content: "198.51.100.1", TTL: 1},
+	}}
+
+	result, err := List(context.Background(), ListSpec{Zone: "example.com", Provider: provider})
+	require.NoError(t, err)
+
+	assert.Len(t, result.Records, 2)
+	assert.Empty(t, result.Addresses)
+	assert.Nil(t, result.Records[0].MatchesHost)
+}
+
+func TestList_ZoneUnsupported(t *testing.T) {
+	_, err := List(context.Background(), ListSpec{Zone: "example.com", Provider: &fakeProvider{}})
+	assert.EqualError(t, err, "Fake provider cannot list whole zones")
+}
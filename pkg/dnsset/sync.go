@@ -0,0 +1,256 @@
+package dnsset
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/yy4382/dns-set/internal/ip"
+)
+
+// Spec describes a sync. TTL nil lets the provider choose its default.
+// Hosts optionally overrides how the address of single domains is found.
+type Spec struct {
+	Domains     []string
+	RecordTypes []RecordType
+	Detector    IPDetector
+	Provider    DNSProvider
+	TTL         *int
+	Proxied     bool
+	Hosts       map[string]Host
+}
+
+// Host describes how the address of one domain differs from the address
+// detected for the spec, for domains pointing at other machines.
+type Host struct {
+	// Detector replaces the detector of the spec; nil keeps it. Hosts with
+	// equal detectors share one detection per record type.
+	Detector IPDetector
+	// IPv6Suffix, when set, replaces the host part of the detected IPv6
+	// address: the AAAA record gets the first IPv6PrefixLength bits (64 when
+	// zero) of the detected address followed by the rest of IPv6Suffix.
+	// A records are not affected.
+	IPv6Suffix       net.IP
+	IPv6PrefixLength int
+}
+
+// Address is the outcome of detecting the address for one record type.
+type Address struct {
+	Type   RecordType `json:"type" yaml:"type"`
+	IP     string     `json:"ip,omitempty" yaml:"ip,omitempty"`
+	Source string     `json:"source" yaml:"source"`
+	Error  string     `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// RecordResult is the outcome of updating one record.
+type RecordResult struct {
+	Domain string     `json:"domain" yaml:"domain"`
+	Type   RecordType `json:"type" yaml:"type"`
+	Action Action     `json:"action" yaml:"action"`
+	Old    []Record   `json:"old,omitempty" yaml:"old,omitempty"`
+	New    *Record    `json:"new,omitempty" yaml:"new,omitempty"`
+	Error  string     `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type Result struct {
+	StartedAt  time.Time      `json:"started_at" yaml:"started_at"`
+	FinishedAt time.Time      `json:"finished_at" yaml:"finished_at"`
+	Addresses  []Address      `json:"addresses" yaml:"addresses"`
+	Records    []RecordResult `json:"records" yaml:"records"`
+}
+
+// Failed returns the number of records that could not be updated.
+func (r *Result) Failed() int {
+	failed := 0
+	for _, record := range r.Records {
+		if record.Action == ActionFailed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Sync detects the address for each record type of the spec and updates the
+// records of all its domains. Failures of single detections or records are
+// reported in the result; an error is only returned for an invalid spec or
+// when ctx is done.
+func Sync(ctx context.Context, spec Spec) (*Result, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+
+	result := &Result{StartedAt: time.Now()}
+
+	for _, recordType := range spec.RecordTypes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Each detector is asked once per record type, however many
+		// domains use it. Host detectors that cannot be map keys are asked
+		// for each of their domains.
+		var specDetection *detection
+		hostDetections := make(map[IPDetector]detection)
+		detect := func(detector IPDetector) detection {
+			address, addr := DetectAddress(detector, recordType)
+			result.Addresses = append(result.Addresses, address)
+			return detection{address: address, ip: addr}
+		}
+
+		for _, domain := range spec.Domains {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			var d detection
+			host := spec.Hosts[domain]
+			switch {
+			case host.Detector == nil:
+				if specDetection == nil {
+					detected := detect(spec.Detector)
+					specDetection = &detected
+				}
+				d = *specDetection
+			default:
+				detected, ok, hashable := cachedDetection(hostDetections, host.Detector)
+				if !ok {
+					detected = detect(host.Detector)
+					if hashable {
+						hostDetections[host.Detector] = detected
+					}
+				}
+				d = detected
+			}
+
+			if d.ip == nil {
+				result.Records = append(result.Records, RecordResult{
+					Domain: domain,
+					Type:   recordType,
+					Action: ActionFailed,
+					Error:  fmt.Sprintf("no %s address: %s", recordType, d.address.Error),
+				})
+				continue
+			}
+
+			addr, err := host.address(recordType, d.ip)
+			if err != nil {
+				result.Records = append(result.Records, RecordResult{
+					Domain: domain,
+					Type:   recordType,
+					Action: ActionFailed,
+					Error:  err.Error(),
+				})
+				continue
+			}
+
+			result.Records = append(result.Records, UpdateRecord(spec.Provider, domain, recordType, addr, spec.TTL, spec.Proxied))
+		}
+	}
+
+	result.FinishedAt = time.Now()
+	return result, nil
+}
+
+type detection struct {
+	address Address
+	ip      net.IP
+}
+
+// cachedDetection looks detector up in cache. hashable is false when the
+// dynamic value of detector cannot be a map key, like a struct holding a
+// slice behind an interface field.
+func cachedDetection(cache map[IPDetector]detection, detector IPDetector) (d detection, ok, hashable bool) {
+	defer func() {
+		if recover() != nil {
+			hashable = false
+		}
+	}()
+	d, ok = cache[detector]
+	return d, ok, true
+}
+
+// address returns the address the record of the host should point at,
+// given the detected address.
+func (h Host) address(recordType RecordType, detected net.IP) (net.IP, error) {
+	if recordType != RecordTypeAAAA || h.IPv6Suffix == nil {
+		return detected, nil
+	}
+
+	prefixLength := h.IPv6PrefixLength
+	if prefixLength == 0 {
+		prefixLength = 64
+	}
+
+	composed, err := ip.ComposeIPv6(detected, prefixLength, h.IPv6Suffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compose IPv6 address: %w", err)
+	}
+	return composed, nil
+}
+
+// DetectAddress asks detector for the address matching recordType. The
+// returned IP is nil when detection failed; the error is in Address.Error.
+func DetectAddress(detector IPDetector, recordType RecordType) (Address, net.IP) {
+	address := Address{Type: recordType, Source: detector.Name()}
+
+	var detected net.IP
+	var err error
+	if recordType == RecordTypeA {
+		detected, err = detector.GetIPv4()
+	} else {
+		detected, err = detector.GetIPv6()
+	}
+
+	if err != nil {
+		address.Error = err.Error()
+		return address, nil
+	}
+
+	address.IP = detected.String()
+	return address, detected
+}
+
+// UpdateRecord points one record at ip and reports what happened.
+func UpdateRecord(provider DNSProvider, domain string, recordType RecordType, ip net.IP, ttl *int, proxied bool) RecordResult {
+	result := RecordResult{Domain: domain, Type: recordType}
+
+	change, err := provider.UpdateRecord(domain, recordType, ip, ttl, proxied)
+	if err != nil {
+		result.Action = ActionFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Action = change.Action
+	result.Old = change.Old
+	result.New = &change.New
+	return result
+}
+
+func (s Spec) validate() error {
+	if s.Provider == nil {
+		return fmt.Errorf("no DNS provider configured")
+	}
+	if s.Detector == nil {
+		return fmt.Errorf("no IP detector configured")
+	}
+	if len(s.Domains) == 0 {
+		return fmt.Errorf("no domains to update")
+	}
+	if len(s.RecordTypes) == 0 {
+		return fmt.Errorf("no record types to update")
+	}
+	return nil
+}
+
+// ParseRecordType parses a record type name such as "A" or "aaaa".
+func ParseRecordType(value string) (RecordType, error) {
+	switch recordType := RecordType(strings.ToUpper(strings.TrimSpace(value))); recordType {
+	case RecordTypeA, RecordTypeAAAA:
+		return recordType, nil
+	default:
+		return "", fmt.Errorf("unsupported record type: %s", value)
+	}
+}
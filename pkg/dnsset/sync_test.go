@@ -0,0 +1,236 @@
+package dnsset
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticDetector struct {
+	ipv4 net.IP
+	ipv6 net.IP
+}
+
+func (s *staticDetector) GetIPv4() (net.IP, error) {
+	if s.ipv4 == nil {
+		return nil, fmt.Errorf("no public IPv4 address found")
+	}
+	return s.ipv4, nil
+}
+
+func (s *staticDetector) GetIPv6() (net.IP, error) {
+	if s.ipv6 == nil {
+		return nil, fmt.Errorf("no public IPv6 address found")
+	}
+	return s.ipv6, nil
+}
+
+func (s *staticDetector) Name() string {
+	return "Static"
+}
+
+type fakeProvider struct {
+	existing map[string]string
+	failing  map[string]bool
+}
+
+func (f *fakeProvider) UpdateRecord(domain string, recordType RecordType, ip net.IP, ttl *int, proxied bool) (Change, error) {
+	if f.failing[domain] {
+		return Change{}, fmt.Errorf("zone not found")
+	}
+
+	record := Record{Name: domain, Type: recordType, Content: ip.String(), TTL: 1, Proxied: proxied}
+
+	content, exists := f.existing[domain]
+	switch {
+	case !exists:
+		return Change{Action: ActionCreated, New: record}, nil
+	case content == ip.String():
+		return Change{Action: ActionUnchanged, Old: []Record{record}, New: record}, nil
+	default:
+		old := record
+		old.Content = content
+		return Change{Action: ActionUpdated, Old: []Record{old}, New: record}, nil
+	}
+}
+
+func (f *fakeProvider) ListRecords(domain string) ([]Record, error) {
+	return nil, nil
+}
+
+func (f *fakeProvider) Name() string {
+	return "Fake"
+}
+
+func TestSync(t *testing.T) {
+	provider := &fakeProvider{
+		existing: map[string]string{"old.example.com": "198.51.100.1", "same.example.com": "203.0.113.7"},
+		failing:  map[string]bool{"broken.example.com": true},
+	}
+
+	result, err := Sync(context.Background(), Spec{
+		Domains:     []string{"new.example.com", "old.example.com", "same.example.com", "broken.example.com"},
+		RecordTypes: []RecordType{RecordTypeA, RecordTypeAAAA},
+		Detector:    &staticDetector{ipv4: net.ParseIP("203.0.113.7")},
+		Provider:    provider,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, result.Addresses, 2)
+	assert.Equal(t, Address{Type: RecordTypeA, IP: "203.0.113.7", Source: "Static"}, result.Addresses[0])
+	assert.Equal(t, RecordTypeAAAA, result.Addresses[1].Type)
+	assert.Equal(t, "no public IPv6 address found", result.Addresses[1].Error)
+
+	actions := make(map[string]Action)
+	for _, record := range result.Records {
+		actions[record.Domain+" "+string(record.Type)] = record.Action
+	}
+
+	assert.Equal(t, map[string]Action{
+		"new.example.com A":       ActionCreated,
+		"old.example.com A":       ActionUpdated,
+		"same.example.com A":      ActionUnchanged,
+		"broken.example.com A":    ActionFailed,
+		"new.example.com AAAA":    ActionFailed,
+		"old.example.com AAAA":    ActionFailed,
+		"same.example.com AAAA":   ActionFailed,
+		"broken.example.com AAAA": ActionFailed,
+	}, actions)
+	assert.Equal(t, 5, result.Failed())
+	assert.False(t, result.FinishedAt.Before(result.StartedAt))
+}
+
+func TestSync_Hosts(t *testing.T) {
+	router := &countingDetector{staticDetector: staticDetector{
+		ipv4: net.ParseIP("203.0.113.7"),
+		ipv6: net.ParseIP("2001:db8:1:2::1"),
+	}}
+
+	result, err := Sync(context.Background(), Spec{
+		Domains:     []string{"router.example.com", "nas.example.com", "printer.example.com", "lan.example.com"},
+		RecordTypes: []RecordType{RecordTypeA, RecordTypeAAAA},
+		Detector:    router,
+		Provider:    &fakeProvider{},
+		Hosts: map[string]Host{
+			"nas.example.com":     {IPv6Suffix: net.ParseIP("::10")},
+			"printer.example.com": {IPv6Suffix: net.ParseIP("::5054:ff:fe12:3456"), IPv6PrefixLength: 56},
+			"lan.example.com":     {Detector: &staticDetector{ipv4: net.ParseIP("192.168.1.20")}},
+		},
+	})
+	require.NoError(t, err)
+
+	contents := make(map[string]string)
+	for _, record := range result.Records {
+		if record.New != nil {
+			contents[record.Domain+" "+string(record.Type)] = record.New.Content
+		}
+	}
+
+	assert.Equal(t, map[string]string{
+		"router.example.com A":     "203.0.113.7",
+		"nas.example.com A":        "203.0.113.7",
+		"printer.example.com A":    "203.0.113.7",
+		"lan.example.com A":        "192.168.1.20",
+		"router.example.com AAAA":  "2001:db8:1:2::1",
+		"nas.example.com AAAA":     "2001:db8:1:2::10",
+		"printer.example.com AAAA": "2001:db8:1:0:5054:ff:fe12:3456",
+	}, contents)
+	assert.Equal(t, 1, result.Failed())
+
+	// One detection per detector and record type.
+	assert.Equal(t, 1, router.ipv4Calls)
+	assert.Equal(t, 1, router.ipv6Calls)
+	assert.Len(t, result.Addresses, 4)
+}
+
+// wrappedDetector is a detector value whose type is comparable while the
+// detector it holds is not.
+type wrappedDetector struct {
+	IPDetector
+}
+
+type chainedDetectors []IPDetector
+
+func (c chainedDetectors) GetIPv4() (net.IP, error) { return c[0].GetIPv4() }
+func (c chainedDetectors) GetIPv6() (net.IP, error) { return c[0].GetIPv6() }
+func (c chainedDetectors) Name() string             { return "Chain" }
+
+func TestSync_HostDetectorIdentity(t *testing.T) {
+	shared := &countingDetector{staticDetector: staticDetector{ipv4: net.ParseIP("192.168.1.20")}}
+	counted := &countingDetector{staticDetector: staticDetector{ipv4: net.ParseIP("192.168.1.40")}}
+	wrapped := wrappedDetector{chainedDetectors{&staticDetector{ipv4: net.ParseIP("192.168.1.30")}}}
+
+	result, err := Sync(context.Background(), Spec{
+		Domains:     []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com", "f.example.com"},
+		RecordTypes: []RecordType{RecordTypeA},
+		Detector:    &staticDetector{ipv4: net.ParseIP("203.0.113.7")},
+		Provider:    &fakeProvider{},
+		Hosts: map[string]Host{
+			"a.example.com": {Detector: shared},
+			"b.example.com": {Detector: shared},
+			"c.example.com": {Detector: wrapped},
+			"d.example.com": {Detector: wrapped},
+			"e.example.com": {Detector: wrappedDetector{counted}},
+			"f.example.com": {Detector: wrappedDetector{counted}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Zero(t, result.Failed())
+
+	// Equal detectors are asked once; detectors that cannot be compared
+	// are asked per domain.
+	assert.Equal(t, 1, shared.ipv4Calls)
+	assert.Equal(t, 1, counted.ipv4Calls)
+	assert.Len(t, result.Addresses, 4)
+}
+
+type countingDetector struct {
+	staticDetector
+	ipv4Calls int
+	ipv6Calls int
+}
+
+func (c *countingDetector) GetIPv4() (net.IP, error) {
+	c.ipv4Calls++
+	return c.staticDetector.GetIPv4()
+}
+
+func (c *countingDetector) GetIPv6() (net.IP, error) {
+	c.ipv6Calls++
+	return c.staticDetector.GetIPv6()
+}
+
+func TestSync_InvalidSpec(t *testing.T) {
+	_, err := Sync(context.Background(), Spec{
+		RecordTypes: []RecordType{RecordTypeA},
+		Detector:    &staticDetector{},
+		Provider:    &fakeProvider{},
+	})
+	assert.EqualError(t, err, "no domains to update")
+}
+
+func TestSync_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Sync(ctx, Spec{
+		Domains:     []string{"example.com"},
+		RecordTypes: []RecordType{RecordTypeA},
+		Detector:    &staticDetector{ipv4: net.ParseIP("203.0.113.7")},
+		Provider:    &fakeProvider{},
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestParseRecordType(t *testing.T) {
+	recordType, err := ParseRecordType(" aaaa ")
+	require.NoError(t, err)
+	assert.Equal(t, RecordTypeAAAA, recordType)
+
+	_, err = ParseRecordType("MX")
+	assert.EqualError(t, err, "unsupported record type: MX")
+}